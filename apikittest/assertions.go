@@ -0,0 +1,78 @@
+package apikittest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	a "github.com/stretchr/testify/assert"
+)
+
+// Problem is a decoded application/problem+json body as sent by the handlers.Send* error functions.
+type Problem struct {
+	Title   string                `json:"title"`
+	Status  int                   `json:"status"`
+	Details handlers.ErrorDetails `json:"details,omitempty"`
+	// RequestId is the ID of the request the error was sent for.
+	RequestId string `json:"request_id,omitempty"`
+}
+
+// AssertProblem asserts that resp is an application/problem+json response with the
+// given status and title and returns the decoded body. The response body is closed.
+func AssertProblem(t testing.TB, resp *http.Response, status int, title string) Problem {
+	t.Helper()
+	assert := a.New(t)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(status, resp.StatusCode, "unexpected status code")
+	assert.Equal(handlers.ContentTypeProblemJson, resp.Header.Get(handlers.HeaderContentType), "unexpected content type")
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unable to read response body: %v", err)
+	}
+
+	var problem Problem
+	err = json.Unmarshal(body, &problem)
+	if err != nil {
+		t.Fatalf("unable to decode problem body %q: %v", string(body), err)
+	}
+
+	assert.Equal(status, problem.Status, "unexpected status in problem body")
+	assert.Equal(title, problem.Title, "unexpected title in problem body")
+
+	return problem
+}
+
+// AssertProblemWithDetails is like AssertProblem but also asserts that the field-level
+// error details equal details.
+func AssertProblemWithDetails(t testing.TB, resp *http.Response, status int, title string, details handlers.ErrorDetails) Problem {
+	t.Helper()
+
+	problem := AssertProblem(t, resp, status, title)
+	AssertErrorDetails(t, problem, details)
+
+	return problem
+}
+
+// AssertErrorDetails asserts that problem contains exactly the given field-level error details.
+func AssertErrorDetails(t testing.TB, problem Problem, details handlers.ErrorDetails) {
+	t.Helper()
+
+	a.New(t).Equal(details, problem.Details, "unexpected error details")
+}
+
+// AssertFieldError asserts that problem contains an error detail for field with the given message.
+func AssertFieldError(t testing.TB, problem Problem, field string, message string) {
+	t.Helper()
+	assert := a.New(t)
+
+	detail, ok := problem.Details[field]
+	if !assert.True(ok, "expected error detail for field %q", field) {
+		return
+	}
+	assert.Equal(message, detail.Message, "unexpected message for field %q", field)
+}
@@ -0,0 +1,107 @@
+package apikittest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+)
+
+// Client sends requests with JSON headers to a base URL. Bodies that are not
+// nil, []byte, string or io.Reader are encoded as JSON.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for baseURL. If httpClient is nil, http.DefaultClient is used.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}
+}
+
+// NewRequest creates a request for path relative to the base URL. Content-Type and
+// Accept are set to application/json.
+func (c *Client) NewRequest(method string, path string, body any) (*http.Request, error) {
+	reader, err := bodyReader(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", handlers.ContentTypeJson)
+	if reader != nil {
+		req.Header.Set(handlers.HeaderContentType, handlers.ContentTypeJson)
+	}
+
+	return req, nil
+}
+
+// Do sends req with the underlying http.Client.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
+}
+
+// Get sends a GET request to path.
+func (c *Client) Get(path string) (*http.Response, error) {
+	return c.send(http.MethodGet, path, nil)
+}
+
+// Post sends a POST request with body to path.
+func (c *Client) Post(path string, body any) (*http.Response, error) {
+	return c.send(http.MethodPost, path, body)
+}
+
+// Put sends a PUT request with body to path.
+func (c *Client) Put(path string, body any) (*http.Response, error) {
+	return c.send(http.MethodPut, path, body)
+}
+
+// Patch sends a PATCH request with body to path.
+func (c *Client) Patch(path string, body any) (*http.Response, error) {
+	return c.send(http.MethodPatch, path, body)
+}
+
+// Delete sends a DELETE request to path.
+func (c *Client) Delete(path string) (*http.Response, error) {
+	return c.send(http.MethodDelete, path, nil)
+}
+
+func (c *Client) send(method string, path string, body any) (*http.Response, error) {
+	req, err := c.NewRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func bodyReader(body any) (io.Reader, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return bytes.NewReader(b), nil
+	case string:
+		return bytes.NewReader([]byte(b)), nil
+	case io.Reader:
+		return b, nil
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode body as JSON: %w", err)
+		}
+		return bytes.NewReader(encoded), nil
+	}
+}
@@ -0,0 +1,55 @@
+package apikittest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestClient_NewRequestEncodesJson(t *testing.T) {
+	assert := a.New(t)
+
+	c := NewClient("http://localhost", nil)
+	req, err := c.NewRequest(http.MethodPost, "/items", map[string]string{"name": "book"})
+	assert.NoError(err)
+
+	assert.Equal("http://localhost/items", req.URL.String())
+	assert.Equal("application/json", req.Header.Get("Content-Type"))
+	assert.Equal("application/json", req.Header.Get("Accept"))
+
+	body, _ := io.ReadAll(req.Body)
+	assert.JSONEq(`{"name":"book"}`, string(body))
+}
+
+func TestClient_NewRequestPassesRawBodies(t *testing.T) {
+	assert := a.New(t)
+
+	c := NewClient("http://localhost", nil)
+	for _, body := range []any{`{"raw":true}`, []byte(`{"raw":true}`), strings.NewReader(`{"raw":true}`)} {
+		req, err := c.NewRequest(http.MethodPut, "/items/1", body)
+		assert.NoError(err)
+		b, _ := io.ReadAll(req.Body)
+		assert.Equal(`{"raw":true}`, string(b))
+	}
+}
+
+func TestClient_NewRequestWithoutBody(t *testing.T) {
+	assert := a.New(t)
+
+	c := NewClient("http://localhost", nil)
+	req, err := c.NewRequest(http.MethodGet, "/items", nil)
+	assert.NoError(err)
+	assert.Empty(req.Header.Get("Content-Type"))
+	assert.Equal("application/json", req.Header.Get("Accept"))
+}
+
+func TestClient_NewRequestFailsForUnencodableBody(t *testing.T) {
+	assert := a.New(t)
+
+	c := NewClient("http://localhost", nil)
+	_, err := c.NewRequest(http.MethodPost, "/items", make(chan int))
+	assert.Error(err)
+}
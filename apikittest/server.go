@@ -0,0 +1,143 @@
+// Package apikittest provides utilities for testing APIs built with go-api-kit,
+// similar to net/http/httptest for plain handlers.
+package apikittest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server"
+)
+
+const startTimeout = 10 * time.Second
+
+// Server is a go-api-kit server listening on an ephemeral port of the loopback interface.
+type Server struct {
+	// Server is the started server.
+	Server *server.Server
+	// URL is the base URL of the server, e.g. http://127.0.0.1:38123, without trailing slash.
+	URL string
+	// AdminURL is the base URL of the admin listener or empty if there is none.
+	AdminURL string
+	// RedirectURL is the base URL of the HTTP to HTTPS redirect listener or empty if
+	// there is none.
+	RedirectURL string
+	// Client sends JSON requests to URL.
+	Client *Client
+}
+
+// NewServer starts a server with the given configuration on an ephemeral port and
+// waits until it accepts connections. The server is stopped when the test finishes.
+// PortOverride and the ports of the admin and redirect listeners, whether set in sc or
+// in the environment, are replaced with ephemeral ports. If TLS is enabled in sc or in
+// the environment, URL uses https.
+func NewServer(t testing.TB, sc *server.ServerConfig) *Server {
+	t.Helper()
+
+	ts, err := start(sc)
+	if err != nil {
+		t.Fatalf("unable to start test server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		err := ts.Server.Stop()
+		if err != nil {
+			t.Errorf("unable to stop test server: %v", err)
+		}
+	})
+
+	return ts
+}
+
+func start(sc *server.ServerConfig) (*Server, error) {
+	if sc == nil {
+		sc = &server.ServerConfig{}
+	}
+
+	env := config.Get()
+
+	// copy the config, so the caller's config is not modified
+	c := *sc
+	c.PortOverride = "0"
+	if c.AdminConfig == nil && env.AdminPort != "" && env.AdminSocketPath == "" {
+		c.AdminConfig = &server.AdminConfig{}
+	}
+	if c.AdminConfig != nil && c.AdminConfig.SocketPath == "" {
+		ac := *c.AdminConfig
+		ac.Port = "0"
+		c.AdminConfig = &ac
+	}
+
+	tlsEnabled := c.TLSConfig != nil || c.TLSCertFile != "" || c.TLSKeyFile != "" ||
+		env.TlsCertFile != "" || env.TlsKeyFile != ""
+	if tlsEnabled && (c.HTTPRedirectPort != "" || env.HttpRedirectPort != "") {
+		c.HTTPRedirectPort = "0"
+	}
+
+	listening := make(chan struct{})
+	listenCallback := c.ListenCallback
+	c.ListenCallback = func() {
+		if listenCallback != nil {
+			listenCallback()
+		}
+		close(listening)
+	}
+
+	s := server.NewServer(&c)
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- s.Start()
+	}()
+
+	select {
+	case <-listening:
+	case err := <-startErr:
+		return nil, err
+	case <-time.After(startTimeout):
+		return nil, fmt.Errorf("server did not start within %s", startTimeout)
+	}
+
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		scheme = "https"
+		// test servers usually use self-signed certificates
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+	}
+
+	ts := &Server{
+		Server: s,
+		URL:    fmt.Sprintf("%s://%s", scheme, loopbackAddr(s.Addr())),
+	}
+	if addr := s.AdminAddr(); addr != nil && addr.Network() == "tcp" {
+		ts.AdminURL = fmt.Sprintf("http://%s", loopbackAddr(addr))
+	}
+	if addr := s.RedirectAddr(); addr != nil {
+		ts.RedirectURL = fmt.Sprintf("http://%s", loopbackAddr(addr))
+	}
+	ts.Client = NewClient(ts.URL, &http.Client{Transport: transport})
+
+	return ts, nil
+}
+
+// Close stops the server. It is called automatically when the test finishes.
+func (ts *Server) Close() error {
+	return ts.Server.Shutdown(context.Background())
+}
+
+// loopbackAddr replaces unspecified addresses like [::]:1234 with the loopback
+// address, so clients can connect on every platform.
+func loopbackAddr(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok || !tcpAddr.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(tcpAddr.Port))
+}
@@ -0,0 +1,145 @@
+package apikittest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	a "github.com/stretchr/testify/assert"
+)
+
+type createItem struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func newItemServer(t *testing.T) *Server {
+	return NewServer(t, &server.ServerConfig{
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("POST /items", handlers.ValidatingHandler(func(w http.ResponseWriter, r *http.Request, item *createItem) {
+				handlers.SendStructAsJson(w, item)
+			}))
+		},
+		AdminConfig: &server.AdminConfig{},
+	})
+}
+
+func TestNewServer_ServesOnEphemeralPort(t *testing.T) {
+	assert := a.New(t)
+
+	ts := newItemServer(t)
+	assert.NotEqual("http://127.0.0.1:8080", ts.URL)
+
+	resp, err := ts.Client.Post("/items", createItem{Name: "book"})
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.JSONEq(`{"name":"book"}`, string(body))
+}
+
+func TestNewServer_ExposesAdminURL(t *testing.T) {
+	assert := a.New(t)
+
+	ts := newItemServer(t)
+	assert.NotEmpty(ts.AdminURL)
+
+	resp, err := http.Get(ts.AdminURL + "/live")
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func TestNewServer_UsesEphemeralRedirectPort(t *testing.T) {
+	assert := a.New(t)
+
+	ts := NewServer(t, &server.ServerConfig{
+		TLSConfig:        newTestTLSConfig(t),
+		HTTPRedirectPort: "8089",
+	})
+	assert.True(strings.HasPrefix(ts.URL, "https://"))
+	assert.NotEmpty(ts.RedirectURL)
+	assert.NotEqual("http://127.0.0.1:8089", ts.RedirectURL)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(ts.RedirectURL + "/items")
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(http.StatusPermanentRedirect, resp.StatusCode)
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(ts.URL, "https://"))
+	assert.Equal("https://127.0.0.1:"+port+"/items", resp.Header.Get("Location"))
+}
+
+func TestNewServer_RunsInParallel(t *testing.T) {
+	assert := a.New(t)
+
+	first := newItemServer(t)
+	second := newItemServer(t)
+	assert.NotEqual(first.URL, second.URL)
+}
+
+func TestAssertProblemWithDetails(t *testing.T) {
+	ts := newItemServer(t)
+
+	resp, err := ts.Client.Post("/items", createItem{})
+	a.New(t).NoError(err)
+
+	problem := AssertProblemWithDetails(t, resp, http.StatusBadRequest, "Bad Request", handlers.ErrorDetails{
+		"name": {Message: "must not be undefined"},
+	})
+	AssertFieldError(t, problem, "name", "must not be undefined")
+}
+
+func TestAssertProblem_NotFound(t *testing.T) {
+	ts := newItemServer(t)
+
+	resp, err := ts.Client.Get("/unknown")
+	a.New(t).NoError(err)
+
+	problem := AssertProblem(t, resp, http.StatusNotFound, "Not Found")
+	AssertErrorDetails(t, problem, nil)
+}
+
+func TestClose_StopsServer(t *testing.T) {
+	assert := a.New(t)
+
+	ts := newItemServer(t)
+	assert.NoError(ts.Close())
+
+	_, err := ts.Client.Get("/items")
+	assert.Error(err)
+}
@@ -15,6 +15,79 @@ type ServerConfig struct {
 	ReadTimeout  int `default:"10" split_words:"true"`  // seconds
 	WriteTimeout int `default:"10" split_words:"true"`  // seconds
 	IdleTimeout  int `default:"620" split_words:"true"` // seconds
+	// TlsCertFile and TlsKeyFile enable HTTPS when both are set.
+	TlsCertFile string `split_words:"true"`
+	TlsKeyFile  string `split_words:"true"`
+	// TlsReloadInterval is the minimum time between checks for changed certificate files.
+	TlsReloadInterval int `default:"10" split_words:"true"` // seconds
+	// HttpRedirectPort starts an additional plain HTTP listener that redirects to HTTPS.
+	HttpRedirectPort string `split_words:"true"`
+	// ShutdownDrainDelay is the time between failing readiness and closing the listeners.
+	ShutdownDrainDelay int `default:"0" split_words:"true"` // seconds
+	// ShutdownTimeout is the deadline for in-flight requests.
+	ShutdownTimeout int `default:"9" split_words:"true"` // seconds
+	// ShutdownHookTimeout is the deadline for shutdown hooks, starting after in-flight requests.
+	ShutdownHookTimeout int `default:"5" split_words:"true"` // seconds
+	// AdminPort starts the admin listener for internal endpoints on this port.
+	AdminPort string `split_words:"true"`
+	// AdminSocketPath starts the admin listener on this unix socket instead of a port.
+	AdminSocketPath string `split_words:"true"`
+	// RequestTimeout is the deadline for handlers to respond. 0 disables the timeout.
+	RequestTimeout int `default:"0" split_words:"true"` // seconds
+	// RateLimit is the number of requests per client IP address and RateLimitWindow. 0 disables the limit.
+	RateLimit       int `default:"0" split_words:"true"`
+	RateLimitWindow int `default:"60" split_words:"true"` // seconds
+	// ConcurrencyLimit is the maximum number of requests in flight. 0 disables the limit.
+	ConcurrencyLimit int `default:"0" split_words:"true"`
+	// ConcurrencyQueueSize is the number of requests that may wait for a free slot.
+	ConcurrencyQueueSize int `default:"100" split_words:"true"`
+	// ConcurrencyQueueTimeout is the time a request waits for a free slot.
+	ConcurrencyQueueTimeout int `default:"100" split_words:"true"` // milliseconds
+	// TraceExporter enables tracing and selects where spans are written: stdout or file.
+	TraceExporter string `split_words:"true"`
+	// TraceFile is the file spans are appended to if TraceExporter is file.
+	TraceFile string `default:"traces.jsonl" split_words:"true"`
+	// MetricsPath is the path of the metrics endpoint on the admin listener. Empty disables the endpoint.
+	MetricsPath string `default:"/metrics" split_words:"true"`
+	// AccessLogFields are the fields of access log entries. Empty logs the default fields.
+	AccessLogFields []string `split_words:"true"`
+	// AccessLogHeaders are request headers added to access log entries.
+	AccessLogHeaders []string `split_words:"true"`
+	// AccessLogSkipPaths are paths or route patterns of requests that are not logged.
+	AccessLogSkipPaths []string `split_words:"true"`
+	// AccessLogSampleRate is the fraction of successful requests that are logged.
+	AccessLogSampleRate float64 `default:"1" split_words:"true"`
+	// AccessLogSlowThreshold logs slower requests at Warn. 0 disables the threshold.
+	AccessLogSlowThreshold int `default:"0" split_words:"true"` // milliseconds
+	// TrustedProxies are CIDR ranges of proxies whose forwarding headers are trusted.
+	TrustedProxies []string `split_words:"true"`
+	// TrustedProxiesHeader is the header trusted proxies set: x-forwarded-for or forwarded.
+	TrustedProxiesHeader string `default:"x-forwarded-for" split_words:"true"`
+	// IpAllowList and IpDenyList are CIDR ranges of clients that may or may not send requests.
+	IpAllowList []string `split_words:"true"`
+	IpDenyList  []string `split_words:"true"`
+	// CompressionMinSize is the minimum size of response bodies that are compressed.
+	CompressionMinSize int `default:"1024" split_words:"true"` // bytes
+	// MaxDecompressedBodySize is the maximum size of compressed request bodies after decompression.
+	MaxDecompressedBodySize int `default:"52428800" split_words:"true"` // bytes
+	// MaxDecompressionRatio is the maximum ratio of decompressed to compressed request body bytes.
+	MaxDecompressionRatio int `default:"100" split_words:"true"`
+	// EtagMaxSize is the maximum size of response bodies that are buffered to compute an ETag.
+	EtagMaxSize int `default:"1048576" split_words:"true"` // bytes
+	// SecurityHeadersPreset selects the security headers: default, api or browser.
+	SecurityHeadersPreset string `default:"default" split_words:"true"`
+	// StrictTransportSecurity, ContentSecurityPolicy, PermissionsPolicy and the following
+	// headers replace the value of the preset. The value off omits the header.
+	StrictTransportSecurity   string `split_words:"true"`
+	ContentSecurityPolicy     string `split_words:"true"`
+	PermissionsPolicy         string `split_words:"true"`
+	FrameOptions              string `split_words:"true"`
+	ReferrerPolicy            string `split_words:"true"`
+	CrossOriginEmbedderPolicy string `split_words:"true"`
+	CrossOriginOpenerPolicy   string `split_words:"true"`
+	CrossOriginResourcePolicy string `split_words:"true"`
+	// HealthCheckInterval is the time for which health check results are cached.
+	HealthCheckInterval int `default:"5" split_words:"true"` // seconds
 }
 
 // ContainerConfig holds container-specific configuration.
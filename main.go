@@ -1,36 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/stfsy/go-api-kit/server"
 	"github.com/stfsy/go-api-kit/utils"
 )
 
 var logger = utils.NewLogger("main")
-var s *server.Server
 
 func main() {
-	startServerNonBlocking()
-	stopServerAfterSignal()
-}
-
-func stopServerAfterSignal() {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-
-	s.Stop()
-
-	logger.Info("Graceful shutdown complete.")
-}
-
-func startServerNonBlocking() {
-	s = server.NewServer(&server.ServerConfig{
+	s := server.NewServer(&server.ServerConfig{
 		MuxCallback: func(*http.ServeMux) {
 			// add your endpoints and middlewares here
 		},
@@ -42,10 +25,13 @@ func startServerNonBlocking() {
 		// define the port manually. If empty the value of env.PORT is used.
 		PortOverride: "8080",
 	})
-	go func() {
-		err := s.Start()
-		if err != nil {
-			panic(fmt.Errorf("unable to start server %w", err))
-		}
-	}()
+
+	// Run blocks until SIGINT or SIGTERM is received and the server has shut down
+	err := s.Run(context.Background())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Server stopped with error: %s", err.Error()))
+		os.Exit(1)
+	}
+
+	logger.Info("Graceful shutdown complete.")
 }
@@ -0,0 +1,43 @@
+// Package adapter converts between negroni middlewares and middlewares in the
+// func(http.Handler) http.Handler style of the standard library.
+package adapter
+
+import (
+	"net/http"
+
+	"github.com/urfave/negroni/v3"
+)
+
+// StdMiddleware is a middleware in the style of the standard library.
+type StdMiddleware = func(http.Handler) http.Handler
+
+// FromStd returns a negroni middleware that runs mw. Because negroni passes the next
+// handler per request, mw is applied to next on every request, so mw should be cheap
+// to apply and keep expensive setup outside of the returned function.
+func FromStd(mw StdMiddleware) negroni.Handler {
+	return negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		mw(next).ServeHTTP(rw, r)
+	})
+}
+
+// ToStd returns a standard library middleware that runs h. The response writer is
+// wrapped in a negroni.ResponseWriter unless it already is one, because middlewares
+// like the access log read the status from it.
+func ToStd(h negroni.Handler) StdMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if _, ok := rw.(negroni.ResponseWriter); !ok {
+				rw = negroni.NewResponseWriter(rw)
+			}
+			h.ServeHTTP(rw, r, next.ServeHTTP)
+		})
+	}
+}
+
+// Chain applies mws to h, so the first middleware runs first.
+func Chain(h http.Handler, mws ...StdMiddleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
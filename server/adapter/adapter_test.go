@@ -0,0 +1,74 @@
+package adapter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func stdHeader(value string) StdMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Add("X-Order", value)
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func TestFromStd_RunsStdMiddlewareInNegroni(t *testing.T) {
+	assert := a.New(t)
+
+	n := negroni.New()
+	n.Use(FromStd(stdHeader("first")))
+	n.Use(FromStd(stdHeader("second")))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(http.StatusTeapot, rec.Code)
+	assert.Equal([]string{"first", "second"}, rec.Header().Values("X-Order"))
+}
+
+func TestToStd_WrapsResponseWriter(t *testing.T) {
+	assert := a.New(t)
+
+	var status int
+	mw := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		next(rw, r)
+		status = rw.(negroni.ResponseWriter).Status()
+	})
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}), stdHeader("std"), ToStd(mw))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(http.StatusCreated, rec.Code)
+	assert.Equal(http.StatusCreated, status)
+	assert.Equal("std", rec.Header().Get("X-Order"))
+}
+
+func TestToStd_StopsChainIfNextIsNotCalled(t *testing.T) {
+	mw := negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		rw.WriteHeader(http.StatusForbidden)
+	})
+
+	called := false
+	h := ToStd(mw)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	a.Equal(t, http.StatusForbidden, rec.Code)
+	a.False(t, called)
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/middlewares"
+	"github.com/urfave/negroni/v3"
+)
+
+// AdminConfig configures an additional listener for internal endpoints such as health,
+// metrics and debug handlers. It is started and stopped together with the main server
+// and does not apply the content type, body length and CSRF checks of the public API.
+type AdminConfig struct {
+	// Port of the admin listener. If empty, uses the API_KIT_ADMIN_PORT environment variable.
+	Port string
+	// SocketPath binds the admin listener to a unix socket instead of a port. If empty,
+	// uses the API_KIT_ADMIN_SOCKET_PATH environment variable.
+	SocketPath string
+	// MetricsPath is the path of the metrics endpoint. If empty, uses the API_KIT_METRICS_PATH
+	// environment variable.
+	MetricsPath string
+	// MuxCallback registers endpoints to the admin mux.
+	MuxCallback func(*http.ServeMux)
+	// MiddlewareCallback customizes the admin middleware stack.
+	MiddlewareCallback func(*negroni.Negroni) *negroni.Negroni
+}
+
+// startAdminServer binds the admin listener and serves it in the background. It is a no-op
+// if neither the server config nor the environment configure an admin listener.
+func (s *Server) startAdminServer(configuration config.Configuration) error {
+	ac := s.serverConfig.AdminConfig
+	if ac == nil {
+		ac = &AdminConfig{}
+	}
+
+	port := configuration.AdminPort
+	if ac.Port != "" {
+		port = ac.Port
+	}
+	socketPath := configuration.AdminSocketPath
+	if ac.SocketPath != "" {
+		socketPath = ac.SocketPath
+	}
+
+	if s.serverConfig.AdminConfig == nil && port == "" && socketPath == "" {
+		return nil
+	}
+	if port == "" && socketPath == "" {
+		return fmt.Errorf("admin listener requires a port or socket path")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /live", handlers.LivenessHandler)
+	mux.HandleFunc("GET /ready", s.health.ReadyHandler)
+	mux.HandleFunc("GET /startup", s.health.StartupHandler)
+	mux.HandleFunc("GET /health", s.health.HealthHandler)
+	metricsPath := configuration.MetricsPath
+	if ac.MetricsPath != "" {
+		metricsPath = ac.MetricsPath
+	}
+	if metricsPath != "" {
+		mux.HandleFunc("GET "+metricsPath, s.metrics.Handler)
+	}
+	if ac.MuxCallback != nil {
+		ac.MuxCallback(mux)
+	}
+
+	n, err := createAdminMiddlewareHandler(s.serverConfig)
+	if err != nil {
+		return err
+	}
+	if ac.MiddlewareCallback != nil {
+		n = ac.MiddlewareCallback(n)
+	}
+	n.UseHandler(createMuxHandler(mux))
+
+	var ln net.Listener
+	if socketPath != "" {
+		s.adminServer = createServer("", n)
+		ln, err = listenUnix(socketPath)
+	} else {
+		s.adminServer = createServer(port, n)
+		ln, err = net.Listen("tcp", s.adminServer.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to bind admin listener: %w", err)
+	}
+	s.adminAddr = ln.Addr()
+
+	logger.Info(fmt.Sprintf("Admin listener on %s", ln.Addr().String()))
+	go func() {
+		err := s.adminServer.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(fmt.Sprintf("Unable to accept incoming connections on admin listener: %s", err.Error()))
+		}
+	}()
+
+	return nil
+}
+
+// listenUnix binds a unix socket, removing a stale socket file left behind by a previous
+// process that did not shut down cleanly. Other file types are never removed.
+func listenUnix(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	if err == nil && info.Mode()&fs.ModeSocket != 0 {
+		err = os.Remove(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+func createAdminMiddlewareHandler(sc *ServerConfig) (*negroni.Negroni, error) {
+	securityHeaders, err := createSecurityHeadersMiddleware(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	recovery := middlewares.NewRecoveryMiddleware()
+	recovery.Reporter = sc.PanicReporter
+
+	n := negroni.New()
+	n.Use(middlewares.NewRequestIdMiddleware())
+	n.Use(recovery)
+	n.Use(middlewares.NewAccessLog())
+	n.Use(securityHeaders)
+	n.Use(middlewares.NewCachePolicyMiddleware())
+	return n, nil
+}
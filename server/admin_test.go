@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	a "github.com/stretchr/testify/assert"
+)
+
+func startAdminTestServer(t *testing.T, ac *AdminConfig) (*Server, string) {
+	srv, addr := startServer(t, &ServerConfig{
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("public"))
+			})
+		},
+		AdminConfig: ac,
+	})
+	t.Cleanup(func() { _ = srv.Stop() })
+	return srv, addr
+}
+
+func TestAdmin_ServesInternalEndpointsOnOwnPort(t *testing.T) {
+	assert := a.New(t)
+
+	srv, addr := startAdminTestServer(t, &AdminConfig{
+		Port: "0",
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("POST /debug/gc", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("done"))
+			})
+		},
+	})
+
+	adminPort := srv.AdminAddr().(*net.TCPAddr).Port
+	adminURL := "http://" + net.JoinHostPort("localhost", strconv.Itoa(adminPort))
+	resp, err := http.Get(adminURL + "/live")
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	ready, err := http.Get(adminURL + "/ready")
+	assert.NoError(err)
+	defer func() { _ = ready.Body.Close() }()
+	assert.Equal(http.StatusOK, ready.StatusCode)
+
+	// the admin stack does not require a JSON content type or a body
+	gc, err := http.Post(adminURL+"/debug/gc", "", nil)
+	assert.NoError(err)
+	defer func() { _ = gc.Body.Close() }()
+	b, _ := io.ReadAll(gc.Body)
+	assert.Equal(http.StatusOK, gc.StatusCode)
+	assert.Equal("done", string(b))
+
+	// internal endpoints are not exposed on the public port
+	public, err := http.Get("http://" + addr + "/live")
+	assert.NoError(err)
+	defer func() { _ = public.Body.Close() }()
+	assert.Equal(http.StatusNotFound, public.StatusCode)
+}
+
+func TestAdmin_ServesMetrics(t *testing.T) {
+	assert := a.New(t)
+
+	srv, addr := startAdminTestServer(t, &AdminConfig{Port: "0"})
+
+	public, err := http.Get("http://" + addr + "/public")
+	assert.NoError(err)
+	_ = public.Body.Close()
+
+	adminPort := srv.AdminAddr().(*net.TCPAddr).Port
+	resp, err := http.Get("http://" + net.JoinHostPort("localhost", strconv.Itoa(adminPort)) + "/metrics")
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(string(b), `http_server_requests_total{method="GET",route="/public",status="2xx"} 1`)
+	assert.Contains(string(b), "# TYPE go_goroutines gauge")
+}
+
+func TestAdmin_SendsConfiguredSecurityHeaders(t *testing.T) {
+	assert := a.New(t)
+
+	headers := security.JSONAPIPreset()
+	srv, addr := startServer(t, &ServerConfig{
+		SecurityHeaders: &headers,
+		AdminConfig:     &AdminConfig{Port: "0"},
+	})
+	t.Cleanup(func() { _ = srv.Stop() })
+
+	public, err := http.Get("http://" + addr + "/")
+	assert.NoError(err)
+	_ = public.Body.Close()
+
+	adminPort := srv.AdminAddr().(*net.TCPAddr).Port
+	resp, err := http.Get("http://" + net.JoinHostPort("localhost", strconv.Itoa(adminPort)) + "/live")
+	assert.NoError(err)
+	_ = resp.Body.Close()
+
+	assert.Equal("no-referrer", resp.Header.Get("Referrer-Policy"))
+	assert.Equal(public.Header.Get("Content-Security-Policy"), resp.Header.Get("Content-Security-Policy"))
+	assert.Equal(public.Header.Get("Referrer-Policy"), resp.Header.Get("Referrer-Policy"))
+}
+
+func TestAdmin_ServesOnUnixSocket(t *testing.T) {
+	assert := a.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "admin.sock")
+	_, _ = startAdminTestServer(t, &AdminConfig{SocketPath: socketPath})
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://admin/live")
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func TestAdmin_RequiresPortOrSocket(t *testing.T) {
+	assert := a.New(t)
+
+	srv := NewServer(&ServerConfig{
+		PortOverride: "0",
+		AdminConfig:  &AdminConfig{},
+	})
+	err := srv.Start()
+	assert.ErrorContains(err, "admin listener requires a port or socket path")
+}
+
+func TestListenUnix_RemovesStaleSocket(t *testing.T) {
+	assert := a.New(t)
+
+	socketPath := filepath.Join(t.TempDir(), "stale.sock")
+	stale, err := net.Listen("unix", socketPath)
+	assert.NoError(err)
+	// keep the socket file around as a crashed process would
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := listenUnix(socketPath)
+	assert.NoError(err)
+	_ = ln.Close()
+}
+
+func TestListenUnix_KeepsRegularFiles(t *testing.T) {
+	assert := a.New(t)
+
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	assert.NoError(os.WriteFile(path, []byte("data"), 0o600))
+
+	_, err := listenUnix(path)
+	assert.Error(err)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.Equal("data", string(b))
+}
@@ -0,0 +1,192 @@
+// Package cache builds Cache-Control policies for responses. Policies are validated, so
+// directives that contradict each other cannot be combined.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type storage int
+
+const (
+	storageNoStore storage = iota
+	storageNoCache
+	storagePrivate
+	storagePublic
+)
+
+// Policy describes how clients and shared caches such as CDNs may store a response.
+// The zero value is NoStore. Policies are immutable, the With methods return copies.
+type Policy struct {
+	storage              storage
+	maxAge               time.Duration
+	sharedMaxAge         time.Duration
+	staleWhileRevalidate time.Duration
+	immutable            bool
+	vary                 []string
+}
+
+// NoStore forbids all caches to store the response. It is the default policy.
+func NoStore() Policy {
+	return Policy{storage: storageNoStore}
+}
+
+// NoCache allows caches to store the response, but they must revalidate it with the
+// server before each use, e.g. with an ETag.
+func NoCache() Policy {
+	return Policy{storage: storageNoCache}
+}
+
+// Private allows only the client to store the response for maxAge.
+func Private(maxAge time.Duration) Policy {
+	return Policy{storage: storagePrivate, maxAge: maxAge}
+}
+
+// Public allows clients and shared caches to store the response for maxAge.
+func Public(maxAge time.Duration) Policy {
+	return Policy{storage: storagePublic, maxAge: maxAge}
+}
+
+// WithSharedMaxAge lets shared caches store a public response for d instead of max-age.
+func (p Policy) WithSharedMaxAge(d time.Duration) Policy {
+	p.sharedMaxAge = d
+	return p
+}
+
+// WithStaleWhileRevalidate lets caches serve a stale response for up to d while they
+// revalidate it in the background.
+func (p Policy) WithStaleWhileRevalidate(d time.Duration) Policy {
+	p.staleWhileRevalidate = d
+	return p
+}
+
+// WithImmutable tells caches that the response never changes while it is fresh, e.g.
+// for versioned resources.
+func (p Policy) WithImmutable() Policy {
+	p.immutable = true
+	return p
+}
+
+// WithVary adds request headers that select between different representations of the
+// response, e.g. Accept-Language.
+func (p Policy) WithVary(headers ...string) Policy {
+	p.vary = append(append([]string(nil), p.vary...), headers...)
+	return p
+}
+
+// Validate returns an error if the directives of p contradict each other.
+func (p Policy) Validate() error {
+	if p.maxAge < 0 || p.sharedMaxAge < 0 || p.staleWhileRevalidate < 0 {
+		return errors.New("durations must not be negative")
+	}
+
+	switch p.storage {
+	case storageNoStore, storageNoCache:
+		name := p.storageDirective()
+		if p.sharedMaxAge > 0 {
+			return fmt.Errorf("s-maxage conflicts with %s", name)
+		}
+		if p.staleWhileRevalidate > 0 {
+			return fmt.Errorf("stale-while-revalidate conflicts with %s", name)
+		}
+		if p.immutable {
+			return fmt.Errorf("immutable conflicts with %s", name)
+		}
+	case storagePrivate:
+		if p.sharedMaxAge > 0 {
+			return errors.New("s-maxage conflicts with private")
+		}
+	}
+
+	if p.immutable && p.maxAge == 0 {
+		return errors.New("immutable requires max-age")
+	}
+	if p.staleWhileRevalidate > 0 && p.maxAge == 0 && p.sharedMaxAge == 0 {
+		return errors.New("stale-while-revalidate requires max-age or s-maxage")
+	}
+
+	for _, header := range p.vary {
+		if !isToken(header) {
+			return fmt.Errorf("vary header %q is invalid", header)
+		}
+	}
+	return nil
+}
+
+// String returns the value of the Cache-Control header.
+func (p Policy) String() string {
+	directives := []string{p.storageDirective()}
+	if p.storage == storagePrivate || p.storage == storagePublic {
+		directives = append(directives, "max-age="+seconds(p.maxAge))
+	}
+	if p.sharedMaxAge > 0 {
+		directives = append(directives, "s-maxage="+seconds(p.sharedMaxAge))
+	}
+	if p.staleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+seconds(p.staleWhileRevalidate))
+	}
+	if p.immutable {
+		directives = append(directives, "immutable")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// Apply sets the Cache-Control header and adds the Vary headers of p.
+func (p Policy) Apply(header http.Header) {
+	header.Set("Cache-Control", p.String())
+
+	for _, v := range p.vary {
+		addVary(header, v)
+	}
+}
+
+// Cacheable reports whether p allows any cache to store the response.
+func (p Policy) Cacheable() bool {
+	return p.storage != storageNoStore
+}
+
+func (p Policy) storageDirective() string {
+	switch p.storage {
+	case storageNoCache:
+		return "no-cache"
+	case storagePrivate:
+		return "private"
+	case storagePublic:
+		return "public"
+	default:
+		return "no-store"
+	}
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
+
+// isToken reports whether s is a valid header field name.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c > 127 || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_String(t *testing.T) {
+	assert := a.New(t)
+
+	assert.Equal("no-store", Policy{}.String())
+	assert.Equal("no-store", NoStore().String())
+	assert.Equal("no-cache", NoCache().String())
+	assert.Equal("private, max-age=60", Private(time.Minute).String())
+	assert.Equal("public, max-age=60, s-maxage=3600, stale-while-revalidate=30",
+		Public(time.Minute).WithSharedMaxAge(time.Hour).WithStaleWhileRevalidate(30*time.Second).String())
+	assert.Equal("public, max-age=31536000, immutable", Public(365*24*time.Hour).WithImmutable().String())
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		policy Policy
+		err    string
+	}{
+		"no-store":                  {NoStore(), ""},
+		"public":                    {Public(time.Minute).WithSharedMaxAge(time.Hour).WithVary("Accept-Language"), ""},
+		"stale with s-maxage":       {Public(0).WithSharedMaxAge(time.Hour).WithStaleWhileRevalidate(time.Minute), ""},
+		"negative":                  {Private(-time.Second), "durations must not be negative"},
+		"no-store with s-maxage":    {NoStore().WithSharedMaxAge(time.Hour), "s-maxage conflicts with no-store"},
+		"no-cache with immutable":   {NoCache().WithImmutable(), "immutable conflicts with no-cache"},
+		"no-store with stale":       {NoStore().WithStaleWhileRevalidate(time.Minute), "stale-while-revalidate conflicts with no-store"},
+		"private with s-maxage":     {Private(time.Minute).WithSharedMaxAge(time.Hour), "s-maxage conflicts with private"},
+		"immutable without max-age": {Public(0).WithImmutable(), "immutable requires max-age"},
+		"stale without max-age":     {Private(0).WithStaleWhileRevalidate(time.Minute), "stale-while-revalidate requires max-age or s-maxage"},
+		"invalid vary":              {Public(time.Minute).WithVary("Accept Language"), `vary header "Accept Language" is invalid`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if tc.err == "" {
+				a.NoError(t, err)
+			} else {
+				a.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Apply(t *testing.T) {
+	assert := a.New(t)
+
+	header := http.Header{}
+	header.Set("Vary", "Accept-Encoding")
+	Public(time.Minute).WithVary("accept-encoding", "Accept-Language").Apply(header)
+	assert.Equal("public, max-age=60", header.Get("Cache-Control"))
+	assert.Equal([]string{"Accept-Encoding", "Accept-Language"}, header.Values("Vary"))
+	assert.Empty(header.Get("Pragma"))
+
+	header = http.Header{}
+	NoStore().Apply(header)
+	assert.Equal("no-store", header.Get("Cache-Control"))
+}
+
+func TestPolicy_WithVaryDoesNotModifyReceiver(t *testing.T) {
+	base := Public(time.Minute).WithVary("Accept")
+	_ = base.WithVary("Accept-Language")
+
+	a.Equal(t, []string{"Accept"}, base.vary)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateReloader serves a TLS certificate loaded from disk and reloads it
+// when the certificate or key file changes. Files are checked lazily during the
+// TLS handshake, at most once per interval, so no background goroutine is needed.
+type certificateReloader struct {
+	certFile  string
+	keyFile   string
+	interval  time.Duration
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string, interval time.Duration) (*certificateReloader, error) {
+	c := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+	}
+
+	err := c.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (c *certificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	due := time.Since(c.lastCheck) >= c.interval
+	cert := c.cert
+	c.mu.RUnlock()
+
+	if !due {
+		return cert, nil
+	}
+
+	err := c.reloadIfChanged()
+	if err != nil {
+		// keep serving the last good certificate, a half-written file must not take the server down
+		logger.Error(fmt.Sprintf("Unable to reload TLS certificate: %s", err.Error()))
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+func (c *certificateReloader) reloadIfChanged() error {
+	certMod, keyMod, err := c.modTimes()
+
+	c.mu.Lock()
+	c.lastCheck = time.Now()
+	unchanged := certMod.Equal(c.certMod) && keyMod.Equal(c.keyMod)
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if unchanged {
+		return nil
+	}
+
+	err = c.reload()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("TLS certificate reloaded")
+	return nil
+}
+
+func (c *certificateReloader) reload() error {
+	certMod, keyMod, err := c.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load key pair: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.certMod = certMod
+	c.keyMod = keyMod
+	c.lastCheck = time.Now()
+	return nil
+}
+
+func (c *certificateReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unable to stat certificate file: %w", err)
+	}
+
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unable to stat key file: %w", err)
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
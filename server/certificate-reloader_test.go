@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificate writes a self-signed certificate for localhost with the given
+// common name to dir and returns the paths of the certificate and key files.
+func writeTestCertificate(t *testing.T, dir string, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatalf("unable to write certificate: %v", err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	if err != nil {
+		t.Fatalf("unable to write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
+
+func commonNameOf(t *testing.T, c *tls.Certificate) string {
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertificateReloader_LoadsInitialCertificate(t *testing.T) {
+	assert := a.New(t)
+
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), "first")
+	reloader, err := newCertificateReloader(certFile, keyFile, time.Hour)
+	assert.NoError(err)
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(err)
+	assert.Equal("first", commonNameOf(t, cert))
+}
+
+func TestCertificateReloader_FailsForMissingFiles(t *testing.T) {
+	assert := a.New(t)
+
+	dir := t.TempDir()
+	_, err := newCertificateReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), time.Hour)
+	assert.Error(err)
+}
+
+func TestCertificateReloader_ReloadsChangedFiles(t *testing.T) {
+	assert := a.New(t)
+
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "first")
+	reloader, err := newCertificateReloader(certFile, keyFile, 0)
+	assert.NoError(err)
+
+	_, _ = writeTestCertificate(t, dir, "second")
+	// make sure the modification time differs even on file systems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(certFile, future, future))
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(err)
+	assert.Equal("second", commonNameOf(t, cert))
+}
+
+func TestCertificateReloader_KeepsCertificateIfReloadFails(t *testing.T) {
+	assert := a.New(t)
+
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "first")
+	reloader, err := newCertificateReloader(certFile, keyFile, 0)
+	assert.NoError(err)
+
+	assert.NoError(os.WriteFile(certFile, []byte("partially written"), 0o600))
+	future := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(certFile, future, future))
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(err)
+	assert.Equal("first", commonNameOf(t, cert))
+}
+
+func TestCertificateReloader_SkipsCheckWithinInterval(t *testing.T) {
+	assert := a.New(t)
+
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "first")
+	reloader, err := newCertificateReloader(certFile, keyFile, time.Hour)
+	assert.NoError(err)
+
+	_, _ = writeTestCertificate(t, dir, "second")
+	future := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(certFile, future, future))
+
+	cert, err := reloader.GetCertificate(nil)
+	assert.NoError(err)
+	assert.Equal("first", commonNameOf(t, cert))
+}
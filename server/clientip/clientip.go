@@ -0,0 +1,71 @@
+// Package clientip carries the IP address and scheme of the client in the request
+// context. They differ from r.RemoteAddr and r.TLS if the server runs behind proxies.
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// Client is the origin of a request.
+type Client struct {
+	// IP is the address of the client.
+	IP netip.Addr
+	// Scheme is the scheme the client used, http or https.
+	Scheme string
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries client.
+func NewContext(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// FromContext returns the client stored in ctx.
+func FromContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(contextKey{}).(Client)
+	return client, ok
+}
+
+// FromRequest returns the client stored in the context of r. If there is none, it is
+// derived from r.RemoteAddr and r.TLS.
+func FromRequest(r *http.Request) Client {
+	if client, ok := FromContext(r.Context()); ok {
+		return client
+	}
+	return Client{IP: RemoteAddr(r), Scheme: requestScheme(r)}
+}
+
+// IP returns the IP address of the client of r as a string, or r.RemoteAddr if it is
+// not an IP address.
+func IP(r *http.Request) string {
+	client := FromRequest(r)
+	if !client.IP.IsValid() {
+		return r.RemoteAddr
+	}
+	return client.IP.String()
+}
+
+// RemoteAddr returns the IP address of the peer of r, which is the last proxy if the
+// server runs behind proxies.
+func RemoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
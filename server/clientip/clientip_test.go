@@ -0,0 +1,40 @@
+package clientip
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestFromRequest_FallsBackToRemoteAddr(t *testing.T) {
+	assert := a.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[::ffff:192.0.2.1]:1234"
+	req.TLS = &tls.ConnectionState{}
+
+	client := FromRequest(req)
+	assert.Equal("192.0.2.1", client.IP.String())
+	assert.Equal("https", client.Scheme)
+}
+
+func TestFromRequest_PrefersContext(t *testing.T) {
+	assert := a.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(NewContext(req.Context(), Client{IP: netip.MustParseAddr("203.0.113.9"), Scheme: "https"}))
+
+	assert.Equal("203.0.113.9", IP(req))
+	assert.Equal("https", FromRequest(req).Scheme)
+}
+
+func TestIP_ReturnsRemoteAddrIfNotAnAddress(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "@"
+
+	a.Equal(t, "@", IP(req))
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderETag              = "ETag"
+	HeaderLastModified      = "Last-Modified"
+	HeaderIfMatch           = "If-Match"
+	HeaderIfNoneMatch       = "If-None-Match"
+	HeaderIfModifiedSince   = "If-Modified-Since"
+	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+)
+
+// StrongETag returns a strong entity tag for body, derived from its SHA-256 hash.
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak entity tag for body. Weak tags stay valid if the representation
+// changes in semantically insignificant ways, e.g. when it is compressed.
+func WeakETag(body []byte) string {
+	return "W/" + StrongETag(body)
+}
+
+// JsonETag returns the entity tag of v encoded the same way SendStructAsJson encodes it.
+func JsonETag(v any, weak bool) (string, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return "", err
+	}
+	if weak {
+		return WeakETag(buf.Bytes()), nil
+	}
+	return StrongETag(buf.Bytes()), nil
+}
+
+// CheckPreconditions evaluates If-Match, If-Unmodified-Since, If-None-Match and
+// If-Modified-Since in the order of RFC 9110 against the current state of the resource.
+// Pass an empty etag and a zero lastModified if the resource does not exist.
+//
+// It returns true if the request may proceed. Otherwise it has already responded with
+// 304 Not Modified for GET and HEAD or with 412 Precondition Failed for other methods.
+func CheckPreconditions(rw http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	exists := etag != "" || !lastModified.IsZero()
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if ifMatch := r.Header.Values(HeaderIfMatch); len(ifMatch) > 0 {
+		if !matchIfMatch(ifMatch, etag) && !(exists && isWildcard(ifMatch)) {
+			SendPreconditionFailed(rw, CreateErrorDetails(HeaderIfMatch, "does not match the current representation"))
+			return false
+		}
+	} else if since, ok := parseHttpDate(r.Header.Get(HeaderIfUnmodifiedSince)); ok && !lastModified.IsZero() {
+		if lastModified.Truncate(time.Second).After(since) {
+			SendPreconditionFailed(rw, CreateErrorDetails(HeaderIfUnmodifiedSince, "resource has been modified since"))
+			return false
+		}
+	}
+
+	notModified := false
+	if ifNoneMatch := r.Header.Values(HeaderIfNoneMatch); len(ifNoneMatch) > 0 {
+		notModified = MatchETag(ifNoneMatch, etag, true) || (exists && isWildcard(ifNoneMatch))
+	} else if since, ok := parseHttpDate(r.Header.Get(HeaderIfModifiedSince)); ok && safe && !lastModified.IsZero() {
+		notModified = !lastModified.Truncate(time.Second).After(since)
+	}
+	if !notModified {
+		return true
+	}
+
+	if safe {
+		if etag != "" {
+			rw.Header().Set(HeaderETag, etag)
+		}
+		if !lastModified.IsZero() {
+			rw.Header().Set(HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+		}
+		rw.WriteHeader(http.StatusNotModified)
+	} else {
+		SendPreconditionFailed(rw, CreateErrorDetails(HeaderIfNoneMatch, "matches the current representation"))
+	}
+	return false
+}
+
+// matchIfMatch compares strongly, but also accepts the weak form of a strong etag. The
+// compression middleware weakens the ETags of compressed responses, so clients that accept
+// gzip only know the weak form of the strong tag the handler computes.
+func matchIfMatch(values []string, etag string) bool {
+	if MatchETag(values, etag, false) {
+		return true
+	}
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, value := range values {
+		for _, candidate := range parseETags(value) {
+			if candidate == "W/"+etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchETag reports whether etag is listed in one of the If-Match or If-None-Match header
+// values. Weak comparison ignores the W/ prefix, strong comparison never matches weak tags.
+func MatchETag(values []string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, value := range values {
+		for _, candidate := range parseETags(value) {
+			if !weak && strings.HasPrefix(candidate, "W/") {
+				continue
+			}
+			if strings.TrimPrefix(candidate, "W/") == opaque {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isWildcard(values []string) bool {
+	return len(values) == 1 && strings.TrimSpace(values[0]) == "*"
+}
+
+// parseETags splits a comma separated list of entity tags. Commas are allowed inside the
+// quoted part of a tag, so the list cannot be split with strings.Split.
+func parseETags(value string) []string {
+	var tags []string
+	for {
+		value = strings.TrimLeft(value, " \t,")
+		if value == "" {
+			return tags
+		}
+		start := 0
+		if strings.HasPrefix(value, "W/") {
+			start = 2
+		}
+		if len(value) <= start || value[start] != '"' {
+			// not a valid tag, skip to the next list element
+			_, value, _ = strings.Cut(value, ",")
+			continue
+		}
+		end := strings.IndexByte(value[start+1:], '"')
+		if end < 0 {
+			return tags
+		}
+		end += start + 2
+		tags = append(tags, value[:end])
+		value = value[end:]
+	}
+}
+
+func parseHttpDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestETags(t *testing.T) {
+	assert := a.New(t)
+
+	strong := StrongETag([]byte(`{"a":1}`))
+	assert.Regexp(`^"[A-Za-z0-9_-]{22}"$`, strong)
+	assert.Equal(strong, StrongETag([]byte(`{"a":1}`)))
+	assert.NotEqual(strong, StrongETag([]byte(`{"a":2}`)))
+	assert.Equal("W/"+strong, WeakETag([]byte(`{"a":1}`)))
+
+	etag, err := JsonETag(map[string]int{"a": 1}, false)
+	assert.NoError(err)
+	assert.Equal(StrongETag([]byte("{\"a\":1}\n")), etag)
+
+	_, err = JsonETag(make(chan int), true)
+	assert.Error(err)
+}
+
+func TestMatchETag(t *testing.T) {
+	assert := a.New(t)
+
+	assert.True(MatchETag([]string{`"x", "y"`}, `"y"`, false))
+	assert.True(MatchETag([]string{`"a,b"`}, `"a,b"`, false))
+	assert.True(MatchETag([]string{`W/"x"`}, `"x"`, true))
+	assert.True(MatchETag([]string{`"x"`}, `W/"x"`, true))
+	assert.False(MatchETag([]string{`W/"x"`}, `"x"`, false))
+	assert.False(MatchETag([]string{`"x"`}, `W/"x"`, false))
+	assert.False(MatchETag([]string{`invalid, "z"`}, `"x"`, true))
+	assert.False(MatchETag([]string{`"x"`}, "", true))
+}
+
+func checkPreconditions(method string, headers map[string]string, etag string, lastModified time.Time) (*httptest.ResponseRecorder, bool) {
+	req := httptest.NewRequest(method, "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	ok := CheckPreconditions(rec, req, etag, lastModified)
+	return rec, ok
+}
+
+func TestCheckPreconditions_IfMatch(t *testing.T) {
+	assert := a.New(t)
+
+	_, ok := checkPreconditions(http.MethodPut, map[string]string{HeaderIfMatch: `"v1"`}, `"v1"`, time.Time{})
+	assert.True(ok)
+
+	rec, ok := checkPreconditions(http.MethodPut, map[string]string{HeaderIfMatch: `"v1"`}, `"v2"`, time.Time{})
+	assert.False(ok)
+	assert.Equal(http.StatusPreconditionFailed, rec.Code)
+
+	_, ok = checkPreconditions(http.MethodPut, map[string]string{HeaderIfMatch: `W/"v1"`}, `W/"v1"`, time.Time{})
+	assert.False(ok)
+
+	// weakened by the compression middleware
+	_, ok = checkPreconditions(http.MethodPut, map[string]string{HeaderIfMatch: `W/"v1"`}, `"v1"`, time.Time{})
+	assert.True(ok)
+
+	_, ok = checkPreconditions(http.MethodPut, map[string]string{HeaderIfMatch: `W/"v1"`}, `"v2"`, time.Time{})
+	assert.False(ok)
+
+	_, ok = checkPreconditions(http.MethodDelete, map[string]string{HeaderIfMatch: "*"}, `"v1"`, time.Time{})
+	assert.True(ok)
+
+	_, ok = checkPreconditions(http.MethodDelete, map[string]string{HeaderIfMatch: "*"}, "", time.Time{})
+	assert.False(ok)
+}
+
+func TestCheckPreconditions_IfUnmodifiedSince(t *testing.T) {
+	assert := a.New(t)
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	_, ok := checkPreconditions(http.MethodPatch, map[string]string{HeaderIfUnmodifiedSince: modified.Format(http.TimeFormat)}, "", modified)
+	assert.True(ok)
+
+	rec, ok := checkPreconditions(http.MethodPatch, map[string]string{HeaderIfUnmodifiedSince: modified.Add(-time.Hour).Format(http.TimeFormat)}, "", modified)
+	assert.False(ok)
+	assert.Equal(http.StatusPreconditionFailed, rec.Code)
+
+	// If-Match takes precedence
+	_, ok = checkPreconditions(http.MethodPatch, map[string]string{
+		HeaderIfMatch:           `"v1"`,
+		HeaderIfUnmodifiedSince: modified.Add(-time.Hour).Format(http.TimeFormat),
+	}, `"v1"`, modified)
+	assert.True(ok)
+
+	_, ok = checkPreconditions(http.MethodPatch, map[string]string{HeaderIfUnmodifiedSince: "invalid"}, "", modified)
+	assert.True(ok)
+}
+
+func TestCheckPreconditions_IfNoneMatch(t *testing.T) {
+	assert := a.New(t)
+
+	rec, ok := checkPreconditions(http.MethodGet, map[string]string{HeaderIfNoneMatch: `W/"v1"`}, `"v1"`, time.Time{})
+	assert.False(ok)
+	assert.Equal(http.StatusNotModified, rec.Code)
+	assert.Equal(`"v1"`, rec.Header().Get(HeaderETag))
+
+	rec, ok = checkPreconditions(http.MethodPut, map[string]string{HeaderIfNoneMatch: "*"}, `"v1"`, time.Time{})
+	assert.False(ok)
+	assert.Equal(http.StatusPreconditionFailed, rec.Code)
+
+	_, ok = checkPreconditions(http.MethodPut, map[string]string{HeaderIfNoneMatch: "*"}, "", time.Time{})
+	assert.True(ok)
+}
+
+func TestCheckPreconditions_IfModifiedSince(t *testing.T) {
+	assert := a.New(t)
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec, ok := checkPreconditions(http.MethodGet, map[string]string{HeaderIfModifiedSince: modified.Format(http.TimeFormat)}, "", modified)
+	assert.False(ok)
+	assert.Equal(http.StatusNotModified, rec.Code)
+	assert.Equal(modified.Format(http.TimeFormat), rec.Header().Get(HeaderLastModified))
+
+	_, ok = checkPreconditions(http.MethodGet, map[string]string{HeaderIfModifiedSince: modified.Add(-time.Second).Format(http.TimeFormat)}, "", modified)
+	assert.True(ok)
+
+	_, ok = checkPreconditions(http.MethodPost, map[string]string{HeaderIfModifiedSince: modified.Format(http.TimeFormat)}, "", modified)
+	assert.True(ok)
+}
@@ -2,8 +2,19 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 )
 
 func MethodNotAllowedHandler(w http.ResponseWriter, _r *http.Request) {
 	SendMethodNotAllowed(w, nil)
 }
+
+// NewMethodNotAllowedHandler returns a handler that responds with 405 Method Not Allowed
+// and lists the allowed methods in the Allow header, as required by RFC 9110.
+func NewMethodNotAllowedHandler(allowedMethods ...string) http.HandlerFunc {
+	allow := strings.Join(allowedMethods, ", ")
+	return func(w http.ResponseWriter, _r *http.Request) {
+		w.Header().Set("Allow", allow)
+		SendMethodNotAllowed(w, nil)
+	}
+}
@@ -28,3 +28,15 @@ func TestMethodNotAllowedHandler(t *testing.T) {
 	assert.Equal(405, payload.Status)
 	assert.Equal("Method Not Allowed", payload.Title)
 }
+
+func TestNewMethodNotAllowedHandler_SetsAllowHeader(t *testing.T) {
+	assert := a.New(t)
+
+	recorder := httptest.NewRecorder()
+	NewMethodNotAllowedHandler("GET", "POST")(recorder, nil)
+	res := recorder.Result()
+
+	assert.Equal(405, res.StatusCode)
+	assert.Equal("GET, POST", res.Header.Get("Allow"))
+	assert.Equal("application/problem+json", res.Header.Get("Content-Type"))
+}
@@ -4,12 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/requestid"
 )
 
 type HttpError struct {
 	Title   string `json:"title"`
 	Status  int    `json:"status"`
 	Details any    `json:"details,omitempty"`
+	// RequestId is set from the X-Request-Id response header, so errors reported by
+	// clients can be correlated with logs.
+	RequestId string `json:"request_id,omitempty"`
 }
 
 type ErrorDetails map[string]ErrorDetail
@@ -22,6 +27,7 @@ type ErrorDetail struct {
 // sendError streams httpError as JSON directly to rw. HttpError's fields are all
 // plain strings/ints/maps, so encoding cannot fail once the status has been written.
 func sendError(rw http.ResponseWriter, httpError HttpError) {
+	httpError.RequestId = rw.Header().Get(requestid.HeaderName)
 	rw.Header().Set(HeaderContentType, ContentTypeProblemJson)
 	rw.WriteHeader(httpError.Status)
 	err := json.NewEncoder(rw).Encode(httpError)
@@ -191,6 +197,15 @@ func SendUnprocessableEntity(rw http.ResponseWriter, details ErrorDetails) {
 	})
 }
 
+// 428 Precondition Required
+func SendPreconditionRequired(rw http.ResponseWriter, details ErrorDetails) {
+	sendError(rw, HttpError{
+		Title:   "Precondition Required",
+		Status:  http.StatusPreconditionRequired,
+		Details: details,
+	})
+}
+
 // 429 Too Many Requests
 func SendTooManyRequests(rw http.ResponseWriter, details ErrorDetails) {
 	sendError(rw, HttpError{
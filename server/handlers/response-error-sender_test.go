@@ -137,6 +137,9 @@ func TestExpectationFailed(t *testing.T) {
 func TestUnprocessableEntity(t *testing.T) {
 	GenericTest(t, SendUnprocessableEntity, http.StatusUnprocessableEntity, "Unprocessable Entity")
 }
+func TestPreconditionRequired(t *testing.T) {
+	GenericTest(t, SendPreconditionRequired, http.StatusPreconditionRequired, "Precondition Required")
+}
 func TestTooManyRequests(t *testing.T) {
 	GenericTest(t, SendTooManyRequests, http.StatusTooManyRequests, "Too Many Requests")
 }
@@ -12,6 +12,7 @@ const (
 	ContentTypeText        = "text/plain; charset=utf-8"
 	ContentTypeJson        = "application/json"
 	ContentTypeProblemJson = "application/problem+json"
+	ContentTypeHealthJson  = "application/health+json"
 )
 
 func send(rw http.ResponseWriter, response []byte, status int) bool {
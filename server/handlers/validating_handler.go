@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -32,6 +33,11 @@ func ValidatingHandler[T any](handler func(http.ResponseWriter, *http.Request, *
 			decoder.DisallowUnknownFields()
 
 			if err := decoder.Decode(&body); err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					SendPayloadTooLarge(w, nil)
+					return
+				}
 				SendBadRequest(w, nil)
 				return
 			}
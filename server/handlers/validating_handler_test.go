@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -115,6 +116,23 @@ func TestValidatingHandler_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestValidatingHandler_BodyTooLarge(t *testing.T) {
+	body := []byte(`{"name":"` + strings.Repeat("a", 100) + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(w, req.Body, 10)
+
+	handler := func(w http.ResponseWriter, r *http.Request, p *testPayload) {
+		t.Error("handler should not be called on a body that is too large")
+	}
+
+	ValidatingHandler[testPayload](handler)(w, req)
+
+	if w.Result().StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Result().StatusCode)
+	}
+}
+
 func TestValidatingHandler_DeleteWithNoBody(t *testing.T) {
 	req := httptest.NewRequest(http.MethodDelete, "/", nil)
 	w := httptest.NewRecorder()
@@ -0,0 +1,44 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+var logger = utils.NewLogger("health")
+
+// HealthHandler responds with the full report of all checks.
+func (r *Registry) HealthHandler(w http.ResponseWriter, req *http.Request) {
+	sendReport(w, r.Health(req.Context()))
+}
+
+// ReadyHandler responds with the readiness report. It fails while the server
+// has not started yet or is shutting down.
+func (r *Registry) ReadyHandler(w http.ResponseWriter, req *http.Request) {
+	sendReport(w, r.Readiness(req.Context()))
+}
+
+// StartupHandler responds with the startup report.
+func (r *Registry) StartupHandler(w http.ResponseWriter, req *http.Request) {
+	sendReport(w, r.Startup(req.Context()))
+}
+
+// sendReport writes report as application/health+json. Reports with status fail
+// are answered with 503, pass and warn with 200.
+func sendReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set(handlers.HeaderContentType, handlers.ContentTypeHealthJson)
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(report)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to encode health report as JSON %s", err.Error()))
+	}
+}
@@ -0,0 +1,62 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler_SendsHealthJson(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "db", Check: func(ctx context.Context) error { return nil }})
+
+	rec := httptest.NewRecorder()
+	r.HealthHandler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("application/health+json", rec.Header().Get("Content-Type"))
+
+	var report Report
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(StatusPass, report.Status)
+	assert.Equal(StatusPass, report.Checks["db"][0].Status)
+}
+
+func TestReadyHandler_Returns503IfCriticalCheckFails(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.MarkStarted()
+	r.Register(Check{Name: "db", Critical: true, Check: func(ctx context.Context) error {
+		return errors.New("down")
+	}})
+
+	rec := httptest.NewRecorder()
+	r.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("application/health+json", rec.Header().Get("Content-Type"))
+}
+
+func TestStartupHandler_Returns503BeforeStart(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+
+	rec := httptest.NewRecorder()
+	r.StartupHandler(rec, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+
+	r.MarkStarted()
+	rec = httptest.NewRecorder()
+	r.StartupHandler(rec, httptest.NewRequest(http.MethodGet, "/startup", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+}
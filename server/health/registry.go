@@ -0,0 +1,210 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCheckTimeout = 5 * time.Second
+
+// Check is a named dependency check, e.g. a database ping.
+type Check struct {
+	// Name identifies the check in the report.
+	Name string
+	// Check returns nil if the dependency is healthy.
+	Check func(ctx context.Context) error
+	// Timeout limits a single run of the check. Defaults to 5 seconds.
+	Timeout time.Duration
+	// Critical checks fail readiness and startup. Failing non-critical checks only
+	// turn the overall status into warn.
+	Critical bool
+}
+
+type checkState struct {
+	check  Check
+	mu     sync.Mutex
+	result CheckResult
+	ran    bool
+}
+
+// Registry runs registered checks and caches their results for a configurable
+// interval, so probes from several load balancers do not multiply the load on
+// the checked dependencies.
+type Registry struct {
+	interval     atomic.Int64
+	mu           sync.RWMutex
+	checks       []*checkState
+	started      atomic.Bool
+	startupDone  atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry returns a registry that re-runs checks at most once per interval.
+func NewRegistry(interval time.Duration) *Registry {
+	r := &Registry{}
+	r.SetInterval(interval)
+	return r
+}
+
+// SetInterval changes the time for which check results are cached.
+func (r *Registry) SetInterval(interval time.Duration) {
+	r.interval.Store(int64(interval))
+}
+
+// Register adds a check. Registering a check with an existing name replaces it.
+func (r *Registry) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = defaultCheckTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.checks {
+		if c.check.Name == check.Name {
+			r.checks[i] = &checkState{check: check}
+			return
+		}
+	}
+	r.checks = append(r.checks, &checkState{check: check})
+}
+
+// MarkStarted signals that the server accepts connections. Startup and readiness
+// fail until it has been called.
+func (r *Registry) MarkStarted() {
+	r.started.Store(true)
+}
+
+// MarkShuttingDown makes readiness fail, so load balancers stop routing traffic.
+func (r *Registry) MarkShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Health runs all due checks and returns the full report. The status is fail if a
+// critical check fails and warn if only non-critical checks fail.
+func (r *Registry) Health(ctx context.Context) Report {
+	return r.run(ctx)
+}
+
+// Readiness is like Health but fails while the server has not started or is shutting down.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	if r.shuttingDown.Load() {
+		return Report{Status: StatusFail, Output: "shutting down"}
+	}
+	if !r.started.Load() {
+		return Report{Status: StatusFail, Output: "starting"}
+	}
+	return r.run(ctx)
+}
+
+// Startup passes once the server has started and every critical check has passed at
+// least once. It keeps passing afterwards, so checks are not run again once started.
+func (r *Registry) Startup(ctx context.Context) Report {
+	if r.startupDone.Load() {
+		return Report{Status: StatusPass}
+	}
+	if !r.started.Load() {
+		return Report{Status: StatusFail, Output: "starting"}
+	}
+
+	report := r.run(ctx)
+	if report.Status != StatusFail {
+		r.startupDone.Store(true)
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make([]*checkState, len(r.checks))
+	copy(checks, r.checks)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.resultFor(ctx, time.Duration(r.interval.Load()))
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusPass,
+		Checks: make(map[string][]CheckResult, len(checks)),
+	}
+	for i, c := range checks {
+		result := results[i]
+		report.Checks[c.check.Name] = []CheckResult{result}
+		if result.Status != StatusFail {
+			continue
+		}
+		if c.check.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusPass {
+			report.Status = StatusWarn
+		}
+	}
+
+	return report
+}
+
+// resultFor returns the cached result or runs the check if the cached result is
+// older than interval. Concurrent callers wait for a single run.
+func (c *checkState) resultFor(ctx context.Context, interval time.Duration) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ran && time.Since(c.result.Time) < interval {
+		return c.result
+	}
+
+	// the result is shared with other callers, so a prober that disconnects
+	// early must not cancel the check
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runCheck(ctx, c.check.Check)
+	duration := time.Since(start)
+
+	c.result = CheckResult{
+		Status:        StatusPass,
+		Time:          start,
+		ObservedValue: duration.Milliseconds(),
+		ObservedUnit:  "ms",
+		Critical:      c.check.Critical,
+	}
+	if err != nil {
+		c.result.Status = StatusFail
+		c.result.Output = err.Error()
+	}
+	c.ran = true
+
+	return c.result
+}
+
+// runCheck runs fn and returns its error, or the context error if fn does not
+// return before the deadline. A panicking check is reported as failed.
+func runCheck(ctx context.Context, fn func(context.Context) error) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestHealth_PassesWithoutChecks(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	assert.Equal(StatusPass, r.Health(context.Background()).Status)
+}
+
+func TestHealth_FailsIfCriticalCheckFails(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "cache", Check: func(ctx context.Context) error { return nil }})
+	r.Register(Check{Name: "db", Critical: true, Check: func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	report := r.Health(context.Background())
+	assert.Equal(StatusFail, report.Status)
+	assert.Equal(StatusPass, report.Checks["cache"][0].Status)
+	assert.Equal(StatusFail, report.Checks["db"][0].Status)
+	assert.Equal("connection refused", report.Checks["db"][0].Output)
+	assert.Equal("ms", report.Checks["db"][0].ObservedUnit)
+	assert.True(report.Checks["db"][0].Critical)
+}
+
+func TestHealth_WarnsIfNonCriticalCheckFails(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "mail", Check: func(ctx context.Context) error {
+		return errors.New("timeout")
+	}})
+
+	assert.Equal(StatusWarn, r.Health(context.Background()).Status)
+}
+
+func TestHealth_CachesResultsForInterval(t *testing.T) {
+	assert := a.New(t)
+
+	var calls atomic.Int32
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "db", Check: func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+
+	r.Health(context.Background())
+	r.Health(context.Background())
+	assert.Equal(int32(1), calls.Load())
+
+	r = NewRegistry(0)
+	r.Register(Check{Name: "db", Check: func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+	r.Health(context.Background())
+	r.Health(context.Background())
+	assert.Equal(int32(3), calls.Load())
+}
+
+func TestHealth_ReportsTimeoutAsFailure(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}})
+
+	report := r.Health(context.Background())
+	assert.Equal(StatusFail, report.Status)
+	assert.Equal(context.DeadlineExceeded.Error(), report.Checks["slow"][0].Output)
+}
+
+func TestHealth_ReportsPanicAsFailure(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "broken", Critical: true, Check: func(ctx context.Context) error {
+		panic("boom")
+	}})
+
+	report := r.Health(context.Background())
+	assert.Equal(StatusFail, report.Status)
+	assert.Equal("check panicked: boom", report.Checks["broken"][0].Output)
+}
+
+func TestHealth_RegisterReplacesCheckWithSameName(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	r.Register(Check{Name: "db", Critical: true, Check: func(ctx context.Context) error { return errors.New("down") }})
+	r.Register(Check{Name: "db", Critical: true, Check: func(ctx context.Context) error { return nil }})
+
+	report := r.Health(context.Background())
+	assert.Equal(StatusPass, report.Status)
+	assert.Len(report.Checks, 1)
+}
+
+func TestReadiness_FailsBeforeStartAndDuringShutdown(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry(time.Minute)
+	assert.Equal(StatusFail, r.Readiness(context.Background()).Status)
+
+	r.MarkStarted()
+	assert.Equal(StatusPass, r.Readiness(context.Background()).Status)
+
+	r.MarkShuttingDown()
+	report := r.Readiness(context.Background())
+	assert.Equal(StatusFail, report.Status)
+	assert.Equal("shutting down", report.Output)
+}
+
+func TestStartup_PassesOnceCriticalChecksPassed(t *testing.T) {
+	assert := a.New(t)
+
+	var healthy atomic.Bool
+	r := NewRegistry(0)
+	r.Register(Check{Name: "db", Critical: true, Check: func(ctx context.Context) error {
+		if !healthy.Load() {
+			return errors.New("not yet")
+		}
+		return nil
+	}})
+
+	r.MarkStarted()
+	assert.Equal(StatusFail, r.Startup(context.Background()).Status)
+
+	healthy.Store(true)
+	assert.Equal(StatusPass, r.Startup(context.Background()).Status)
+
+	// startup stays passed even if the dependency fails later
+	healthy.Store(false)
+	assert.Equal(StatusPass, r.Startup(context.Background()).Status)
+	assert.Equal(StatusFail, r.Readiness(context.Background()).Status)
+}
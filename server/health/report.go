@@ -0,0 +1,31 @@
+package health
+
+import "time"
+
+// Status is the health status of a check or the whole service as defined by the
+// draft "Health Check Response Format for HTTP APIs" (application/health+json).
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Report is the response body of the health endpoints.
+type Report struct {
+	Status Status                   `json:"status"`
+	Output string                   `json:"output,omitempty"`
+	Checks map[string][]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the cached result of a single check. The duration of the last
+// run is reported as observed value in milliseconds.
+type CheckResult struct {
+	Status        Status    `json:"status"`
+	Time          time.Time `json:"time"`
+	ObservedValue int64     `json:"observedValue"`
+	ObservedUnit  string    `json:"observedUnit"`
+	Output        string    `json:"output,omitempty"`
+	Critical      bool      `json:"critical"`
+}
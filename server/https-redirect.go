@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+)
+
+// createHttpsRedirectHandler answers every plain HTTP request with a 308 Permanent
+// Redirect to the same host and URI on the HTTPS port. 308 is used instead of 301
+// so clients keep method and body when following the redirect.
+func createHttpsRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if host == "" {
+			handlers.SendBadRequest(w, nil)
+			return
+		}
+
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestHttpsRedirectHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		httpsPort string
+		host      string
+		target    string
+		want      string
+	}{
+		{"default https port", "443", "example.com", "/items?page=2", "https://example.com/items?page=2"},
+		{"custom https port", "8443", "example.com:8080", "/items", "https://example.com:8443/items"},
+		{"ipv6 host", "8443", "[::1]:8080", "/", "https://[::1]:8443/"},
+		{"ipv6 host on default port", "443", "[::1]", "/", "https://[::1]/"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := a.New(t)
+
+			req := httptest.NewRequest(http.MethodPost, tc.target, nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+
+			createHttpsRedirectHandler(tc.httpsPort).ServeHTTP(rec, req)
+
+			assert.Equal(http.StatusPermanentRedirect, rec.Code)
+			assert.Equal(tc.want, rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHttpsRedirectHandler_RejectsMissingHost(t *testing.T) {
+	assert := a.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = ""
+	rec := httptest.NewRecorder()
+
+	createHttpsRedirectHandler("443").ServeHTTP(rec, req)
+
+	assert.Equal(http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+)
+
+// ContentTypeTextExposition is the content type of the Prometheus text exposition format.
+const ContentTypeTextExposition = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler responds with all metrics in the Prometheus text exposition format.
+func (r *Registry) Handler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set(handlers.HeaderContentType, ContentTypeTextExposition)
+	err := r.Write(w)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to write metrics %s", err.Error()))
+	}
+}
+
+// Write writes all metrics in the Prometheus text exposition format to w. Metrics are
+// sorted by name and series by label values, so the output is stable.
+func (r *Registry) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.sortedFamilies() {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	if f.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	if f.fn != nil {
+		writeSample(w, f.name, nil, nil, f.fn())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.typ != typeHistogram {
+			writeSample(w, f.name, f.labelNames, s.labelValues, s.value)
+			continue
+		}
+
+		names := slices.Concat(f.labelNames, []string{"le"})
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.bucketCounts[i]
+			writeSample(w, f.name+"_bucket", names, slices.Concat(s.labelValues, []string{formatValue(bound)}), float64(cumulative))
+		}
+		writeSample(w, f.name+"_bucket", names, slices.Concat(s.labelValues, []string{"+Inf"}), float64(s.count))
+		writeSample(w, f.name+"_sum", f.labelNames, s.labelValues, s.value)
+		writeSample(w, f.name+"_count", f.labelNames, s.labelValues, float64(s.count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames []string, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 {
+		w.WriteByte('{')
+		for i, l := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabelValue(labelValues[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatValue(value))
+	w.WriteByte('\n')
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
@@ -0,0 +1,245 @@
+// Package metrics records counters, gauges and histograms and exposes them in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+var logger = utils.NewLogger("metrics")
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// Registry holds the metrics of a service. Registering a metric with the name, type
+// and labels of an existing metric returns the existing metric, so packages can
+// register the metrics they use without coordinating.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+
+	runtimeMu         sync.Mutex
+	runtimeRegistered bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// family is a metric with all its label combinations.
+type family struct {
+	name       string
+	help       string
+	typ        metricType
+	labelNames []string
+	buckets    []float64
+	fn         func() float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// histograms only, counts are not cumulative
+	bucketCounts []uint64
+	count        uint64
+}
+
+// Counter returns a counter with the given labels, registering it if necessary.
+func (r *Registry) Counter(name string, help string, labelNames ...string) (*Counter, error) {
+	f, err := r.register(&family{name: name, help: help, typ: typeCounter, labelNames: labelNames})
+	if err != nil {
+		return nil, err
+	}
+	return &Counter{f}, nil
+}
+
+// Gauge returns a gauge with the given labels, registering it if necessary.
+func (r *Registry) Gauge(name string, help string, labelNames ...string) (*Gauge, error) {
+	f, err := r.register(&family{name: name, help: help, typ: typeGauge, labelNames: labelNames})
+	if err != nil {
+		return nil, err
+	}
+	return &Gauge{f}, nil
+}
+
+// Histogram returns a histogram with the given upper bucket bounds and labels, registering
+// it if necessary. If buckets is empty, DefaultDurationBuckets are used.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labelNames ...string) (*Histogram, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	if !slices.IsSorted(buckets) {
+		return nil, fmt.Errorf("buckets of metric %s are not sorted", name)
+	}
+	if slices.Contains(labelNames, "le") {
+		return nil, fmt.Errorf("label le of metric %s is reserved", name)
+	}
+	f, err := r.register(&family{name: name, help: help, typ: typeHistogram, labelNames: labelNames, buckets: buckets})
+	if err != nil {
+		return nil, err
+	}
+	return &Histogram{f}, nil
+}
+
+// GaugeFunc registers a gauge whose value is read from fn when the metrics are collected.
+func (r *Registry) GaugeFunc(name string, help string, fn func() float64) error {
+	_, err := r.register(&family{name: name, help: help, typ: typeGauge, fn: fn})
+	return err
+}
+
+// CounterFunc registers a counter whose value is read from fn when the metrics are collected.
+func (r *Registry) CounterFunc(name string, help string, fn func() float64) error {
+	_, err := r.register(&family{name: name, help: help, typ: typeCounter, fn: fn})
+	return err
+}
+
+func (r *Registry) register(f *family) (*family, error) {
+	if !metricNamePattern.MatchString(f.name) {
+		return nil, fmt.Errorf("metric name %s is invalid", f.name)
+	}
+	for _, l := range f.labelNames {
+		if !labelNamePattern.MatchString(l) || strings.HasPrefix(l, "__") {
+			return nil, fmt.Errorf("label name %s of metric %s is invalid", l, f.name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.families[f.name]; ok {
+		if existing.fn != nil || f.fn != nil || existing.typ != f.typ ||
+			!slices.Equal(existing.labelNames, f.labelNames) || !slices.Equal(existing.buckets, f.buckets) {
+			return nil, fmt.Errorf("metric %s already exists", f.name)
+		}
+		return existing, nil
+	}
+
+	f.series = map[string]*series{}
+	r.families[f.name] = f
+	return f, nil
+}
+
+// sortedFamilies returns the families sorted by name.
+func (r *Registry) sortedFamilies() []*family {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	return families
+}
+
+// with calls fn with the series of labelValues, creating it if necessary. Samples with
+// the wrong number of label values are dropped and logged.
+func (f *family) with(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(f.labelNames) {
+		logger.Error(fmt.Sprintf("Metric %s expects %d label values but got %d", f.name, len(f.labelNames), len(labelValues)))
+		return
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		if f.typ == typeHistogram {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// Counter is a value that only increases, e.g. the number of requests.
+type Counter struct {
+	f *family
+}
+
+// Inc increments the counter of the given label values by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter of the given label values by v. Negative values are ignored.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.with(labelValues, func(s *series) { s.value += v })
+}
+
+// Gauge is a value that increases and decreases, e.g. the number of requests in flight.
+type Gauge struct {
+	f *family
+}
+
+// Set sets the gauge of the given label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.with(labelValues, func(s *series) { s.value = v })
+}
+
+// Add adds v to the gauge of the given label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.with(labelValues, func(s *series) { s.value += v })
+}
+
+// Inc increments the gauge of the given label values by 1.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge of the given label values by 1.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Histogram counts observations in buckets, e.g. request latencies.
+type Histogram struct {
+	f *family
+}
+
+// Observe adds v to the histogram of the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.with(labelValues, func(s *series) {
+		// values above the last bound are only counted in +Inf, which equals count
+		if i, _ := slices.BinarySearch(h.f.buckets, v); i < len(s.bucketCounts) {
+			s.bucketCounts[i]++
+		}
+		s.value += v
+		s.count++
+	})
+}
+
+var (
+	// DefaultDurationBuckets are buckets for latencies in seconds from 5ms to 10s.
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// DefaultSizeBuckets are buckets for sizes in bytes from 100 bytes to 10 MB.
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
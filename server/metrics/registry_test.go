@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WritesTextExposition(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry()
+	jobs, err := r.Counter("jobs_total", "Number of jobs.", "queue")
+	assert.NoError(err)
+	jobs.Inc("mail")
+	jobs.Add(2, "mail")
+	jobs.Inc(`a"b`)
+
+	size, err := r.Gauge("queue_size", "Size of the queue.\nIn jobs.")
+	assert.NoError(err)
+	size.Set(3)
+	size.Dec()
+
+	latency, err := r.Histogram("job_duration_seconds", "", []float64{0.1, 1})
+	assert.NoError(err)
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(5)
+
+	var buf bytes.Buffer
+	assert.NoError(r.Write(&buf))
+	assert.Equal(`# TYPE job_duration_seconds histogram
+job_duration_seconds_bucket{le="0.1"} 1
+job_duration_seconds_bucket{le="1"} 2
+job_duration_seconds_bucket{le="+Inf"} 3
+job_duration_seconds_sum 5.55
+job_duration_seconds_count 3
+# HELP jobs_total Number of jobs.
+# TYPE jobs_total counter
+jobs_total{queue="a\"b"} 1
+jobs_total{queue="mail"} 3
+# HELP queue_size Size of the queue.\nIn jobs.
+# TYPE queue_size gauge
+queue_size 2
+`, buf.String())
+}
+
+func TestRegistry_ReturnsExistingMetric(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry()
+	first, err := r.Counter("jobs_total", "", "queue")
+	assert.NoError(err)
+	second, err := r.Counter("jobs_total", "", "queue")
+	assert.NoError(err)
+	first.Inc("mail")
+	second.Inc("mail")
+
+	var buf bytes.Buffer
+	assert.NoError(r.Write(&buf))
+	assert.Contains(buf.String(), `jobs_total{queue="mail"} 2`)
+}
+
+func TestRegistry_RejectsInvalidMetrics(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry()
+	_, err := r.Counter("jobs_total", "", "queue")
+	assert.NoError(err)
+
+	_, err = r.Gauge("jobs_total", "", "queue")
+	assert.EqualError(err, "metric jobs_total already exists")
+	_, err = r.Counter("jobs_total", "")
+	assert.EqualError(err, "metric jobs_total already exists")
+	_, err = r.Counter("jobs-total", "")
+	assert.EqualError(err, "metric name jobs-total is invalid")
+	_, err = r.Counter("failures_total", "", "__name")
+	assert.EqualError(err, "label name __name of metric failures_total is invalid")
+	_, err = r.Histogram("duration_seconds", "", []float64{1, 0.1})
+	assert.EqualError(err, "buckets of metric duration_seconds are not sorted")
+	_, err = r.Histogram("duration_seconds", "", nil, "le")
+	assert.EqualError(err, "label le of metric duration_seconds is reserved")
+}
+
+func TestRegistry_DropsSamplesWithWrongLabelCount(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry()
+	jobs, _ := r.Counter("jobs_total", "", "queue")
+	jobs.Inc()
+
+	var buf bytes.Buffer
+	assert.NoError(r.Write(&buf))
+	assert.Equal("# TYPE jobs_total counter\n", buf.String())
+}
+
+func TestRegisterRuntimeMetrics(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry()
+	assert.NoError(RegisterRuntimeMetrics(r))
+
+	rec := httptest.NewRecorder()
+	r.Handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(ContentTypeTextExposition, rec.Header().Get("Content-Type"))
+	assert.Contains(rec.Body.String(), "# TYPE go_goroutines gauge")
+	assert.Contains(rec.Body.String(), "# TYPE go_gc_cycles_total counter")
+}
+
+func TestRegisterRuntimeMetrics_IsIdempotent(t *testing.T) {
+	assert := a.New(t)
+
+	r := NewRegistry()
+	assert.NoError(RegisterRuntimeMetrics(r))
+	assert.NoError(RegisterRuntimeMetrics(r))
+
+	conflicting := NewRegistry()
+	_, err := conflicting.Counter("go_goroutines", "Conflicts with the runtime gauge.")
+	assert.NoError(err)
+	assert.Error(RegisterRuntimeMetrics(conflicting))
+}
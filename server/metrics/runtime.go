@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// memStatsMaxAge limits how often runtime.ReadMemStats, which stops the world, is called.
+const memStatsMaxAge = time.Second
+
+type memStatsCache struct {
+	mu    sync.Mutex
+	stats runtime.MemStats
+	read  time.Time
+}
+
+func (c *memStatsCache) get() runtime.MemStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.read) > memStatsMaxAge {
+		runtime.ReadMemStats(&c.stats)
+		c.read = time.Now()
+	}
+	return c.stats
+}
+
+// RegisterRuntimeMetrics registers gauges and counters for goroutines, memory and garbage
+// collection of the Go runtime. Registering them again in the same registry does nothing.
+func RegisterRuntimeMetrics(r *Registry) error {
+	r.runtimeMu.Lock()
+	defer r.runtimeMu.Unlock()
+	if r.runtimeRegistered {
+		return nil
+	}
+
+	err := registerRuntimeMetrics(r)
+	if err != nil {
+		return err
+	}
+	r.runtimeRegistered = true
+	return nil
+}
+
+func registerRuntimeMetrics(r *Registry) error {
+	cache := &memStatsCache{}
+	memStat := func(fn func(s runtime.MemStats) float64) func() float64 {
+		return func() float64 { return fn(cache.get()) }
+	}
+
+	gauges := []struct {
+		name string
+		help string
+		fn   func() float64
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", func() float64 { return float64(runtime.NumGoroutine()) }},
+		{"go_gomaxprocs", "Number of OS threads that can execute Go code simultaneously.", func() float64 { return float64(runtime.GOMAXPROCS(0)) }},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", memStat(func(s runtime.MemStats) float64 { return float64(s.Alloc) })},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", memStat(func(s runtime.MemStats) float64 { return float64(s.HeapInuse) })},
+		{"go_memstats_heap_objects", "Number of allocated objects.", memStat(func(s runtime.MemStats) float64 { return float64(s.HeapObjects) })},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from the system.", memStat(func(s runtime.MemStats) float64 { return float64(s.Sys) })},
+	}
+	for _, g := range gauges {
+		err := r.GaugeFunc(g.name, g.help, g.fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := r.CounterFunc("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.",
+		memStat(func(s runtime.MemStats) float64 { return float64(s.TotalAlloc) }))
+	if err != nil {
+		return err
+	}
+	err = r.CounterFunc("go_gc_cycles_total", "Number of completed garbage collection cycles.",
+		memStat(func(s runtime.MemStats) float64 { return float64(s.NumGC) }))
+	if err != nil {
+		return err
+	}
+	return r.CounterFunc("go_gc_pause_seconds_total", "Total time the world was stopped for garbage collection.",
+		memStat(func(s runtime.MemStats) float64 { return float64(s.PauseTotalNs) / float64(time.Second) }))
+}
@@ -1,23 +1,139 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/clientip"
+	"github.com/stfsy/go-api-kit/server/requestid"
+	"github.com/stfsy/go-api-kit/server/route"
 	"github.com/stfsy/go-api-kit/utils"
 	"github.com/urfave/negroni/v3"
 )
 
 var logger = utils.NewLogger("access-log-middleware")
 
+// StatusClientClosedRequest is logged for requests whose client went away before a
+// response was written.
+const StatusClientClosedRequest = 499
+
+// AccessLogField is a field of the access log entry.
+type AccessLogField string
+
+const (
+	AccessLogMethod    AccessLogField = "method"
+	AccessLogPath      AccessLogField = "path"
+	AccessLogQuery     AccessLogField = "query"
+	AccessLogRoute     AccessLogField = "route"
+	AccessLogProto     AccessLogField = "proto"
+	AccessLogStatus    AccessLogField = "status"
+	AccessLogDuration  AccessLogField = "duration"
+	AccessLogBytes     AccessLogField = "bytes"
+	AccessLogRemoteIP  AccessLogField = "remote_ip"
+	AccessLogUserAgent AccessLogField = "user_agent"
+	AccessLogRequestId AccessLogField = "request_id"
+)
+
+var (
+	// DefaultAccessLogFields are logged if no fields are configured.
+	DefaultAccessLogFields = []AccessLogField{
+		AccessLogMethod, AccessLogPath, AccessLogProto, AccessLogStatus,
+		AccessLogDuration, AccessLogUserAgent, AccessLogRequestId,
+	}
+	// DefaultRedactedHeaders are headers whose values are never logged.
+	DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+)
+
+const redacted = "[REDACTED]"
+
+// AccessLogOptions configure an AccessLogMiddleware.
+type AccessLogOptions struct {
+	// Fields are logged for each request. Defaults to DefaultAccessLogFields.
+	Fields []AccessLogField
+	// Headers are request headers logged as header.<name>.
+	Headers []string
+	// RedactHeaders are headers logged as [REDACTED]. Defaults to DefaultRedactedHeaders.
+	RedactHeaders []string
+	// SkipPaths are paths or route patterns of requests that are not logged, e.g. health checks.
+	SkipPaths []string
+	// SampleRate is the fraction of successful requests that are logged. Failed and slow
+	// requests are always logged. Values <= 0 or >= 1 log all requests.
+	SampleRate float64
+	// Levels sets the log level by status class, e.g. 5 for 5xx. Classes without a level
+	// are logged at Info.
+	Levels map[int]slog.Level
+	// SlowThreshold logs requests that take longer at Warn or above, flagged as slow. Zero
+	// disables the threshold.
+	SlowThreshold time.Duration
+}
+
 // AccessLogMiddleware logs each request via the shared structured logger instead of
 // negroni's default Logger, which renders a text/template and takes a global mutex
-// on every request.
-type AccessLogMiddleware struct{}
+// on every request. Requests whose client went away before a response was written are
+// logged with status 499.
+type AccessLogMiddleware struct {
+	fields        []AccessLogField
+	headers       []string
+	redact        map[string]bool
+	skip          []string
+	sampleRate    float64
+	levels        map[int]slog.Level
+	slowThreshold time.Duration
+	logger        *slog.Logger
+}
 
-// NewAccessLog returns a new AccessLogMiddleware instance
+// NewAccessLog returns a new AccessLogMiddleware configured by API_KIT_ACCESS_LOG_FIELDS,
+// API_KIT_ACCESS_LOG_HEADERS, API_KIT_ACCESS_LOG_SKIP_PATHS, API_KIT_ACCESS_LOG_SAMPLE_RATE
+// and API_KIT_ACCESS_LOG_SLOW_THRESHOLD. 5xx responses are logged at Error.
 func NewAccessLog() *AccessLogMiddleware {
-	return &AccessLogMiddleware{}
+	c := config.Get()
+	fields := make([]AccessLogField, 0, len(c.AccessLogFields))
+	for _, f := range c.AccessLogFields {
+		fields = append(fields, AccessLogField(strings.TrimSpace(f)))
+	}
+
+	return NewAccessLogWithOptions(AccessLogOptions{
+		Fields:        fields,
+		Headers:       c.AccessLogHeaders,
+		SkipPaths:     c.AccessLogSkipPaths,
+		SampleRate:    c.AccessLogSampleRate,
+		Levels:        map[int]slog.Level{5: slog.LevelError},
+		SlowThreshold: time.Duration(c.AccessLogSlowThreshold) * time.Millisecond,
+	})
+}
+
+// NewAccessLogWithOptions returns a new AccessLogMiddleware configured by options.
+func NewAccessLogWithOptions(options AccessLogOptions) *AccessLogMiddleware {
+	m := &AccessLogMiddleware{
+		fields:        options.Fields,
+		headers:       options.Headers,
+		redact:        map[string]bool{},
+		skip:          options.SkipPaths,
+		sampleRate:    options.SampleRate,
+		levels:        options.Levels,
+		slowThreshold: options.SlowThreshold,
+		logger:        logger,
+	}
+	if len(m.fields) == 0 {
+		m.fields = DefaultAccessLogFields
+	}
+
+	redact := options.RedactHeaders
+	if redact == nil {
+		redact = DefaultRedactedHeaders
+	}
+	for _, h := range redact {
+		m.redact[http.CanonicalHeaderKey(h)] = true
+	}
+
+	return m
 }
 
 func (m *AccessLogMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
@@ -25,18 +141,78 @@ func (m *AccessLogMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request,
 
 	next(rw, r)
 
-	status := 0
-	res, ok := rw.(negroni.ResponseWriter)
-	if ok {
+	pattern := ""
+	if resolved := route.FromContext(r.Context()); resolved != nil {
+		pattern = resolved.Pattern
+	}
+	if m.skipped(r.URL.Path, pattern) {
+		return
+	}
+
+	duration := time.Since(start)
+	status, size, written := 0, 0, false
+	if res, ok := rw.(negroni.ResponseWriter); ok {
 		status = res.Status()
+		size = res.Size()
+		written = res.Written()
+	}
+	// a written status is what the client received, even if it went away afterwards
+	if !written && errors.Is(r.Context().Err(), context.Canceled) {
+		status = StatusClientClosedRequest
+	}
+
+	slow := m.slowThreshold > 0 && duration > m.slowThreshold
+	if !slow && status < http.StatusBadRequest && m.sampleRate > 0 && m.sampleRate < 1 && rand.Float64() >= m.sampleRate {
+		return
 	}
 
-	logger.Info("request",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"proto", r.Proto,
-		"status", status,
-		"duration", time.Since(start),
-		"user_agent", r.UserAgent(),
-	)
+	level, ok := m.levels[status/100]
+	if !ok {
+		level = slog.LevelInfo
+	}
+
+	attrs := make([]slog.Attr, 0, len(m.fields)+len(m.headers)+1)
+	for _, f := range m.fields {
+		switch f {
+		case AccessLogMethod:
+			attrs = append(attrs, slog.String(string(f), r.Method))
+		case AccessLogPath:
+			attrs = append(attrs, slog.String(string(f), r.URL.Path))
+		case AccessLogQuery:
+			attrs = append(attrs, slog.String(string(f), r.URL.RawQuery))
+		case AccessLogRoute:
+			attrs = append(attrs, slog.String(string(f), pattern))
+		case AccessLogProto:
+			attrs = append(attrs, slog.String(string(f), r.Proto))
+		case AccessLogStatus:
+			attrs = append(attrs, slog.Int(string(f), status))
+		case AccessLogDuration:
+			attrs = append(attrs, slog.Duration(string(f), duration))
+		case AccessLogBytes:
+			attrs = append(attrs, slog.Int(string(f), size))
+		case AccessLogRemoteIP:
+			attrs = append(attrs, slog.String(string(f), clientip.IP(r)))
+		case AccessLogUserAgent:
+			attrs = append(attrs, slog.String(string(f), r.UserAgent()))
+		case AccessLogRequestId:
+			attrs = append(attrs, slog.String(string(f), requestid.FromContext(r.Context())))
+		}
+	}
+	for _, h := range m.headers {
+		value := r.Header.Get(h)
+		if value != "" && m.redact[http.CanonicalHeaderKey(h)] {
+			value = redacted
+		}
+		attrs = append(attrs, slog.String("header."+strings.ToLower(h), value))
+	}
+	if slow {
+		level = max(level, slog.LevelWarn)
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+
+	m.logger.LogAttrs(r.Context(), level, "request", attrs...)
+}
+
+func (m *AccessLogMiddleware) skipped(path string, pattern string) bool {
+	return slices.Contains(m.skip, path) || (pattern != "" && slices.Contains(m.skip, pattern))
 }
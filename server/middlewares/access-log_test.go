@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveAccessLog(m *AccessLogMiddleware, req *http.Request, fn http.HandlerFunc) []map[string]any {
+	var buf bytes.Buffer
+	m.logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(fn)
+	n.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entries []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry map[string]any
+		_ = dec.Decode(&entry)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLogMiddleware_LogsSelectedFieldsAndHeaders(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewAccessLogWithOptions(AccessLogOptions{
+		Fields:  []AccessLogField{AccessLogRoute, AccessLogQuery, AccessLogBytes, AccessLogRemoteIP, AccessLogStatus},
+		Headers: []string{"Authorization", "X-Tenant"},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/1?expand=true", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tenant", "acme")
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{Pattern: "GET /users/{id}"}))
+
+	entries := serveAccessLog(m, req, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	assert.Len(entries, 1)
+	entry := entries[0]
+	assert.Equal("INFO", entry["level"])
+	assert.Equal("GET /users/{id}", entry["route"])
+	assert.Equal("expand=true", entry["query"])
+	assert.Equal(float64(5), entry["bytes"])
+	assert.Equal("192.0.2.1", entry["remote_ip"])
+	assert.Equal("[REDACTED]", entry["header.authorization"])
+	assert.Equal("acme", entry["header.x-tenant"])
+	assert.NotContains(entry, "method")
+}
+
+func TestAccessLogMiddleware_SkipsPathsAndPatterns(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewAccessLogWithOptions(AccessLogOptions{SkipPaths: []string{"/health", "GET /live"}})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	assert.Empty(serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/health", nil), ok))
+
+	req := httptest.NewRequest(http.MethodGet, "/live", nil)
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{Pattern: "GET /live"}))
+	assert.Empty(serveAccessLog(m, req, ok))
+
+	assert.Len(serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/users", nil), ok), 1)
+}
+
+func TestAccessLogMiddleware_SamplesOnlySuccessfulRequests(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewAccessLogWithOptions(AccessLogOptions{SampleRate: 0.0000001})
+
+	assert.Empty(serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {}))
+	assert.Len(serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}), 1)
+}
+
+func TestAccessLogMiddleware_LevelByStatusAndSlowRequests(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewAccessLogWithOptions(AccessLogOptions{
+		Levels:        map[int]slog.Level{4: slog.LevelDebug, 5: slog.LevelError},
+		SlowThreshold: 10 * time.Millisecond,
+	})
+
+	entries := serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	assert.Equal("ERROR", entries[0]["level"])
+
+	entries = serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	assert.Equal("DEBUG", entries[0]["level"])
+
+	entries = serveAccessLog(m, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	assert.Equal("WARN", entries[0]["level"])
+	assert.Equal(true, entries[0]["slow"])
+}
+
+func TestAccessLogMiddleware_LogsClientClosedRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	entries := serveAccessLog(NewAccessLogWithOptions(AccessLogOptions{}), req, func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+	})
+
+	a.Equal(t, float64(StatusClientClosedRequest), entries[0]["status"])
+}
+
+func TestAccessLogMiddleware_KeepsWrittenStatusOfClosedRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	entries := serveAccessLog(NewAccessLogWithOptions(AccessLogOptions{}), req, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		cancel()
+	})
+
+	a.Equal(t, float64(http.StatusCreated), entries[0]["status"])
+}
@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/cache"
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/urfave/negroni/v3"
+)
+
+// CachePolicyMiddleware sets the Cache-Control header of responses from the cache policy
+// of the matched route, see route.Cache. Routes without a policy use the default policy,
+// which forbids caching. Error responses are never cached, so a CDN does not keep serving
+// a temporary failure. Responses with a CSP nonce are never cached either, because a cache
+// would serve the same nonce to every client. This requires the security headers
+// middleware to run before.
+type CachePolicyMiddleware struct {
+	defaultPolicy cache.Policy
+}
+
+// NewCachePolicyMiddleware returns a middleware with the default policy cache.NoStore.
+func NewCachePolicyMiddleware() *CachePolicyMiddleware {
+	return &CachePolicyMiddleware{defaultPolicy: cache.NoStore()}
+}
+
+// NewCachePolicyMiddlewareWithDefault returns a middleware that applies policy to routes
+// without a cache policy. It returns an error if the policy is invalid.
+func NewCachePolicyMiddlewareWithDefault(policy cache.Policy) (*CachePolicyMiddleware, error) {
+	err := policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &CachePolicyMiddleware{defaultPolicy: policy}, nil
+}
+
+func (m *CachePolicyMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if security.NonceFromContext(r.Context()) != "" {
+		applyCachePolicy(rw.Header(), cache.NoStore())
+		next(rw, r)
+		return
+	}
+
+	options := route.OptionsFromContext(r.Context())
+	policy := m.defaultPolicy
+	if options.CachePolicy != nil {
+		policy = *options.CachePolicy
+	}
+
+	applyCachePolicy(rw.Header(), policy)
+	if policy.Cacheable() {
+		if nrw, ok := rw.(negroni.ResponseWriter); ok {
+			nrw.Before(func(w negroni.ResponseWriter) {
+				if w.Status() >= http.StatusBadRequest {
+					applyCachePolicy(w.Header(), cache.NoStore())
+				}
+			})
+		}
+	}
+
+	next(rw, r)
+}
+
+// applyCachePolicy sets the headers of policy. Responses that must not be stored also get
+// the headers of noCacheHeaders for HTTP/1.0 caches and proxies like nginx.
+func applyCachePolicy(header http.Header, policy cache.Policy) {
+	policy.Apply(header)
+	if policy.Cacheable() {
+		return
+	}
+	// keys are in canonical form, so write directly to skip Set's canonicalization
+	for k, v := range noCacheHeaders {
+		header[k] = []string{v}
+	}
+}
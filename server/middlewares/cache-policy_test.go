@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/cache"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveCachePolicy(m *CachePolicyMiddleware, opts []route.Option, fn http.HandlerFunc) *httptest.ResponseRecorder {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(fn)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if len(opts) > 0 {
+		req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+			Pattern: "GET /",
+			Options: route.Options{}.Apply(opts...),
+		}))
+	}
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+func sendOk(w http.ResponseWriter, r *http.Request) {
+	handlers.SendText(w, "ok")
+}
+
+func TestCachePolicyMiddleware_DefaultsToNoStore(t *testing.T) {
+	assert := a.New(t)
+
+	rec := serveCachePolicy(NewCachePolicyMiddleware(), nil, sendOk)
+
+	assert.Equal("no-store, no-cache, must-revalidate, proxy-revalidate", rec.Header().Get("Cache-Control"))
+	assert.Equal("no-cache", rec.Header().Get("Pragma"))
+	assert.Equal("no-store", rec.Header().Get("Surrogate-Control"))
+}
+
+func TestCachePolicyMiddleware_UsesRoutePolicy(t *testing.T) {
+	assert := a.New(t)
+
+	policy := cache.Public(time.Minute).WithSharedMaxAge(time.Hour).WithVary("Accept-Language")
+	rec := serveCachePolicy(NewCachePolicyMiddleware(), []route.Option{route.Cache(policy)}, sendOk)
+
+	assert.Equal("public, max-age=60, s-maxage=3600", rec.Header().Get("Cache-Control"))
+	assert.Equal("Accept-Language", rec.Header().Get("Vary"))
+	assert.Empty(rec.Header().Get("Pragma"))
+}
+
+func TestCachePolicyMiddleware_DoesNotCacheErrors(t *testing.T) {
+	assert := a.New(t)
+
+	rec := serveCachePolicy(NewCachePolicyMiddleware(), []route.Option{route.Cache(cache.Public(time.Minute))}, func(w http.ResponseWriter, r *http.Request) {
+		handlers.SendServiceUnavailable(w, nil)
+	})
+
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("no-store, no-cache, must-revalidate, proxy-revalidate", rec.Header().Get("Cache-Control"))
+	assert.Equal("no-store", rec.Header().Get("Surrogate-Control"))
+}
+
+func TestNewCachePolicyMiddlewareWithDefault(t *testing.T) {
+	assert := a.New(t)
+
+	_, err := NewCachePolicyMiddlewareWithDefault(cache.Private(0).WithImmutable())
+	assert.EqualError(err, "immutable requires max-age")
+
+	m, err := NewCachePolicyMiddlewareWithDefault(cache.Private(time.Minute))
+	assert.NoError(err)
+	rec := serveCachePolicy(m, nil, sendOk)
+	assert.Equal("private, max-age=60", rec.Header().Get("Cache-Control"))
+}
+
+func TestCachePolicyMiddleware_NeverCachesResponsesWithNonce(t *testing.T) {
+	assert := a.New(t)
+
+	c := security.BrowserAppPreset()
+	csp := c.ContentSecurityPolicy.ScriptSrc(security.SourceNonce)
+	c.ContentSecurityPolicy = &csp
+
+	securityHeaders, err := NewSecurityHeadersMiddleware()
+	assert.NoError(err)
+	n := negroni.New()
+	n.Use(securityHeaders)
+	n.Use(NewCachePolicyMiddleware())
+	n.UseHandlerFunc(sendOk)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+		Pattern: "GET /",
+		Options: route.Options{}.Apply(route.Cache(cache.Public(time.Hour)), route.SecurityHeaders(c)),
+	}))
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+
+	assert.Contains(rec.Header().Get("Content-Security-Policy"), "'nonce-")
+	assert.Equal("no-store, no-cache, must-revalidate, proxy-revalidate", rec.Header().Get("Cache-Control"))
+}
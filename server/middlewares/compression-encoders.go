@@ -0,0 +1,104 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+)
+
+// Encoder compresses responses with a content coding, e.g. to add brotli or zstd.
+type Encoder interface {
+	// Encoding returns the name of the content coding used in Accept-Encoding and
+	// Content-Encoding.
+	Encoding() string
+	// NewWriter returns a writer that compresses to w. It is closed once the response
+	// is complete. If it implements Flush() error, it is flushed when the handler flushes.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// GzipEncoder compresses with gzip. Writers are pooled.
+type GzipEncoder struct {
+	level int
+	pool  sync.Pool
+}
+
+// NewGzipEncoder returns a gzip encoder with the level of compress/gzip. Invalid levels
+// fall back to gzip.DefaultCompression.
+func NewGzipEncoder(level int) *GzipEncoder {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	return &GzipEncoder{level: level}
+}
+
+func (e *GzipEncoder) Encoding() string {
+	return "gzip"
+}
+
+func (e *GzipEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	gw, ok := e.pool.Get().(*gzip.Writer)
+	if ok {
+		gw.Reset(w)
+	} else {
+		// the level is validated by the constructor
+		gw, _ = gzip.NewWriterLevel(w, e.level)
+	}
+	return &pooledWriter{writer: gw, pool: &e.pool}
+}
+
+// DeflateEncoder compresses with deflate, which HTTP defines as the zlib format. Writers
+// are pooled.
+type DeflateEncoder struct {
+	level int
+	pool  sync.Pool
+}
+
+// NewDeflateEncoder returns a deflate encoder with the level of compress/zlib. Invalid
+// levels fall back to zlib.DefaultCompression.
+func NewDeflateEncoder(level int) *DeflateEncoder {
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		level = zlib.DefaultCompression
+	}
+	return &DeflateEncoder{level: level}
+}
+
+func (e *DeflateEncoder) Encoding() string {
+	return "deflate"
+}
+
+func (e *DeflateEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	zw, ok := e.pool.Get().(*zlib.Writer)
+	if ok {
+		zw.Reset(w)
+	} else {
+		// the level is validated by the constructor
+		zw, _ = zlib.NewWriterLevel(w, e.level)
+	}
+	return &pooledWriter{writer: zw, pool: &e.pool}
+}
+
+type resettableWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// pooledWriter returns the writer to its pool once it is closed.
+type pooledWriter struct {
+	writer resettableWriter
+	pool   *sync.Pool
+}
+
+func (w *pooledWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+func (w *pooledWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+func (w *pooledWriter) Close() error {
+	err := w.writer.Close()
+	w.pool.Put(w.writer)
+	return err
+}
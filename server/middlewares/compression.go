@@ -0,0 +1,305 @@
+package middlewares
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/urfave/negroni/v3"
+)
+
+// DefaultCompressionContentTypes are the media types compressed if no content types are
+// configured. Types ending with /* match all subtypes.
+var DefaultCompressionContentTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/health+json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/*",
+}
+
+// CompressionOptions configure a CompressionMiddleware.
+type CompressionOptions struct {
+	// Encoders are the supported content codings, in the order preferred if a client
+	// accepts several with the same quality. Defaults to gzip and deflate.
+	Encoders []Encoder
+	// MinSize is the minimum size of a response body in bytes to be compressed.
+	MinSize int
+	// ContentTypes are the media types that are compressed. Defaults to
+	// DefaultCompressionContentTypes.
+	ContentTypes []string
+}
+
+// CompressionMiddleware compresses responses with the content coding the client prefers
+// according to the q-values of its Accept-Encoding header. Responses are buffered until
+// MinSize bytes are written to decide whether they are compressed. Responses that are
+// smaller, have another content type, already have a Content-Encoding, are partial or
+// are flushed before reaching MinSize, e.g. server-sent events, are sent unchanged.
+// Strong ETags of compressed responses are made weak, because the compressed bytes
+// differ from the uncompressed representation.
+type CompressionMiddleware struct {
+	encoders     []Encoder
+	minSize      int
+	contentTypes []string
+}
+
+// NewCompressionMiddleware returns a middleware with gzip and deflate at the default
+// compression level and the minimum size set by API_KIT_COMPRESSION_MIN_SIZE.
+func NewCompressionMiddleware() *CompressionMiddleware {
+	return NewCompressionMiddlewareWithOptions(CompressionOptions{
+		MinSize: config.Get().CompressionMinSize,
+	})
+}
+
+// NewCompressionMiddlewareWithOptions returns a middleware configured by options.
+func NewCompressionMiddlewareWithOptions(options CompressionOptions) *CompressionMiddleware {
+	m := &CompressionMiddleware{
+		encoders: options.Encoders,
+		minSize:  max(options.MinSize, 1),
+	}
+	if len(m.encoders) == 0 {
+		m.encoders = []Encoder{NewGzipEncoder(-1), NewDeflateEncoder(-1)}
+	}
+
+	contentTypes := options.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultCompressionContentTypes
+	}
+	for _, ct := range contentTypes {
+		m.contentTypes = append(m.contentTypes, normalizeMediaType(ct))
+	}
+	return m
+}
+
+func (m *CompressionMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	encoder := m.negotiate(r.Header.Values("Accept-Encoding"))
+	if r.Method == http.MethodHead {
+		encoder = nil
+	}
+
+	// responses that are not compressed are wrapped, too, so every variant of a
+	// compressible response gets Vary: Accept-Encoding
+	cw := &compressWriter{ResponseWriter: rw, middleware: m, encoder: encoder}
+	defer cw.close()
+
+	// later middlewares read the status from a negroni.ResponseWriter
+	next(negroni.NewResponseWriter(cw), r)
+}
+
+// negotiate returns the encoder with the highest quality in Accept-Encoding or nil if
+// the client accepts none of them.
+func (m *CompressionMiddleware) negotiate(acceptEncoding []string) Encoder {
+	if len(acceptEncoding) == 0 {
+		return nil
+	}
+
+	qualities := map[string]float64{}
+	for _, value := range acceptEncoding {
+		for _, part := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" {
+				continue
+			}
+			qualities[coding] = parseQuality(params)
+		}
+	}
+
+	var best Encoder
+	bestQuality := 0.0
+	for _, e := range m.encoders {
+		q, ok := qualities[e.Encoding()]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQuality {
+			best, bestQuality = e, q
+		}
+	}
+	return best
+}
+
+// parseQuality returns the q parameter of params, 1 if it is missing and 0 if it is invalid.
+func parseQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+func (m *CompressionMiddleware) compressible(contentType string) bool {
+	mediaType := normalizeMediaType(contentType)
+	for _, allowed := range m.contentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the start of the response until it can decide whether to
+// compress it. Without an encoder the response is never compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	middleware *CompressionMiddleware
+	encoder    Encoder
+
+	status  int
+	buf     []byte
+	decided bool
+	writer  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// informational responses are sent right away
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	if !bodyAllowed(code) {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.writer != nil {
+			return w.writer.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.middleware.minSize {
+		err := w.decide(true)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends the response unchanged if it was not compressed yet, because handlers
+// that flush usually stream and need the data to reach the client right away.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		_ = w.decide(false)
+	}
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection to the handler, e.g. for WebSocket upgrades. Nothing is
+// compressed afterwards.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.decided = true
+	w.buf = nil
+	return conn, brw, nil
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide writes the header and the buffered body, compressed if allowed is true and
+// the response qualifies.
+func (w *compressWriter) decide(allowed bool) error {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// sniff before compressing, otherwise net/http would sniff the compressed bytes
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	eligible := bodyAllowed(w.status) && w.status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" && header.Get("Content-Range") == "" &&
+		w.middleware.compressible(header.Get("Content-Type"))
+	if eligible {
+		addVary(header, "Accept-Encoding")
+	}
+
+	if !eligible || !allowed || w.encoder == nil || len(w.buf) < w.middleware.minSize {
+		w.ResponseWriter.WriteHeader(w.status)
+		if len(w.buf) == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	header.Set("Content-Encoding", w.encoder.Encoding())
+	header.Del("Content-Length")
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.writer = w.encoder.NewWriter(w.ResponseWriter)
+	_, err := w.writer.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided && w.status != 0 {
+		_ = w.decide(false)
+	}
+	if w.writer != nil {
+		_ = w.writer.Close()
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= 200
+}
+
+// addVary adds value to the Vary header unless it is already listed.
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
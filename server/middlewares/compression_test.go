@@ -0,0 +1,210 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+var largeJson = `{"items":"` + strings.Repeat("a", 2048) + `"}`
+
+func serveCompression(m *CompressionMiddleware, acceptEncoding string, fn http.HandlerFunc) *httptest.ResponseRecorder {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(fn)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+func writeJson(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Content-Length", "123")
+		w.Header().Set("ETag", `"v1"`)
+		// write in two parts to cross the threshold in between
+		_, _ = w.Write([]byte(body[:len(body)/2]))
+		_, _ = w.Write([]byte(body[len(body)/2:]))
+	}
+}
+
+func TestCompressionMiddleware_CompressesWithGzip(t *testing.T) {
+	assert := a.New(t)
+
+	rec := serveCompression(NewCompressionMiddlewareWithOptions(CompressionOptions{MinSize: 1024}), "gzip, deflate", writeJson(largeJson))
+
+	assert.Equal("gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal("Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Empty(rec.Header().Get("Content-Length"))
+	assert.Equal(`W/"v1"`, rec.Header().Get("ETag"))
+
+	gr, err := gzip.NewReader(rec.Body)
+	assert.NoError(err)
+	body, _ := io.ReadAll(gr)
+	assert.Equal(largeJson, string(body))
+}
+
+func TestCompressionMiddleware_HonoursQualities(t *testing.T) {
+	assert := a.New(t)
+
+	rec := serveCompression(NewCompressionMiddlewareWithOptions(CompressionOptions{}), "gzip;q=0.5, deflate;q=0.8", writeJson(largeJson))
+
+	assert.Equal("deflate", rec.Header().Get("Content-Encoding"))
+	zr, err := zlib.NewReader(rec.Body)
+	assert.NoError(err)
+	body, _ := io.ReadAll(zr)
+	assert.Equal(largeJson, string(body))
+
+	rec = serveCompression(NewCompressionMiddlewareWithOptions(CompressionOptions{}), "gzip;q=0, *", writeJson(largeJson))
+	assert.Equal("deflate", rec.Header().Get("Content-Encoding"))
+
+	rec = serveCompression(NewCompressionMiddlewareWithOptions(CompressionOptions{}), "br, identity", writeJson(largeJson))
+	assert.Empty(rec.Header().Get("Content-Encoding"))
+	assert.Equal(largeJson, rec.Body.String())
+}
+
+func TestCompressionMiddleware_AddsVaryToUncompressedVariants(t *testing.T) {
+	assert := a.New(t)
+	m := NewCompressionMiddlewareWithOptions(CompressionOptions{MinSize: 1024})
+
+	rec := serveCompression(m, "", writeJson(largeJson))
+	assert.Empty(rec.Header().Get("Content-Encoding"))
+	assert.Equal("Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Equal(`"v1"`, rec.Header().Get("ETag"))
+	assert.Equal(largeJson, rec.Body.String())
+
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(writeJson(largeJson))
+	req := httptest.NewRequest(http.MethodHead, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	assert.Empty(rec.Header().Get("Content-Encoding"))
+	assert.Equal("Accept-Encoding", rec.Header().Get("Vary"))
+
+	rec = serveCompression(m, "", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte(largeJson))
+	})
+	assert.Empty(rec.Header().Get("Vary"))
+}
+
+func TestCompressionMiddleware_SkipsResponses(t *testing.T) {
+	m := NewCompressionMiddlewareWithOptions(CompressionOptions{MinSize: 1024})
+
+	for name, fn := range map[string]http.HandlerFunc{
+		"small": writeJson(`{"a":1}`),
+		"content type": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(largeJson))
+		},
+		"already encoded": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = w.Write([]byte(largeJson))
+		},
+		"streaming": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte(largeJson))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := serveCompression(m, "gzip", fn)
+
+			a.NotEqual(t, "gzip", rec.Header().Get("Content-Encoding"))
+			a.NotContains(t, rec.Body.String(), "\x1f\x8b")
+		})
+	}
+}
+
+func TestCompressionMiddleware_SniffsContentTypeAndKeepsStatus(t *testing.T) {
+	assert := a.New(t)
+
+	rec := serveCompression(NewCompressionMiddlewareWithOptions(CompressionOptions{}), "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("<html>" + strings.Repeat("a", 2048) + "</html>"))
+	})
+
+	assert.Equal(http.StatusCreated, rec.Code)
+	assert.Equal("text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal("gzip", rec.Header().Get("Content-Encoding"))
+}
+
+type upperEncoder struct{}
+
+func (upperEncoder) Encoding() string { return "upper" }
+
+func (upperEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	return &upperWriter{w}
+}
+
+type upperWriter struct{ w io.Writer }
+
+func (u *upperWriter) Write(p []byte) (int, error) { return u.w.Write(bytes.ToUpper(p)) }
+func (u *upperWriter) Close() error                { return nil }
+
+func TestCompressionMiddleware_CustomEncoder(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewCompressionMiddlewareWithOptions(CompressionOptions{Encoders: []Encoder{upperEncoder{}}, MinSize: 2})
+	rec := serveCompression(m, "upper", writeJson(`{"a":"b"}`))
+
+	assert.Equal("upper", rec.Header().Get("Content-Encoding"))
+	assert.Equal(`{"A":"B"}`, rec.Body.String())
+}
+
+// serveHijack sends a request with Accept-Encoding through m to a handler that hijacks the
+// connection and answers with a raw 101 Switching Protocols. It returns the status line.
+func serveHijack(t *testing.T, m negroni.Handler) string {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a type assertion like WebSocket libraries do, it does not unwrap the writer
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("writer is not a http.Hijacker")
+			return
+		}
+		conn, brw, err := hj.Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %s", err)
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+	})
+	srv := httptest.NewServer(n)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nAccept-Encoding: gzip\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+func TestCompressionMiddleware_SupportsHijack(t *testing.T) {
+	a.Equal(t, "HTTP/1.1 101 Switching Protocols", serveHijack(t, NewCompressionMiddlewareWithOptions(CompressionOptions{})))
+}
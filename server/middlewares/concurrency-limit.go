@@ -0,0 +1,228 @@
+package middlewares
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/urfave/negroni/v3"
+)
+
+// priorityClasses is the number of route.PriorityClass values, from PriorityLow to PriorityCritical.
+const priorityClasses = int(route.PriorityCritical-route.PriorityLow) + 1
+
+// ConcurrencyLimitOptions configure a ConcurrencyLimitMiddleware.
+type ConcurrencyLimitOptions struct {
+	// Limit decides how many requests may be in flight. Required.
+	Limit ConcurrencyLimit
+	// QueueSize is the number of requests that may wait for a free slot. Zero sheds
+	// requests immediately when the limit is reached.
+	QueueSize int
+	// QueueTimeout is the time a request waits for a free slot before it is shed.
+	QueueTimeout time.Duration
+	// RetryAfter is sent in the Retry-After header of shed requests. Defaults to 1 second.
+	RetryAfter time.Duration
+}
+
+// ConcurrencyLimitMiddleware caps the number of requests in flight. Requests above the
+// limit wait in a queue ordered by the route.PriorityClass of their route and are shed
+// with 503 Service Unavailable and Retry-After if no slot frees up in time. If the queue
+// is full, a queued request of a lower priority class is shed to make room.
+type ConcurrencyLimitMiddleware struct {
+	limit        ConcurrencyLimit
+	queueSize    int
+	queueTimeout time.Duration
+	retryAfter   string
+
+	mu       sync.Mutex
+	inFlight int
+	queued   int
+	queues   [priorityClasses][]*concurrencyWaiter
+}
+
+type concurrencyWaiter struct {
+	// ready receives true if the request may proceed and false if it was shed
+	ready chan bool
+}
+
+// NewConcurrencyLimitMiddleware returns a middleware with a static limit set by
+// API_KIT_CONCURRENCY_LIMIT and the queue configured by API_KIT_CONCURRENCY_QUEUE_SIZE
+// and API_KIT_CONCURRENCY_QUEUE_TIMEOUT.
+func NewConcurrencyLimitMiddleware() *ConcurrencyLimitMiddleware {
+	c := config.Get()
+	return NewConcurrencyLimitMiddlewareWithOptions(ConcurrencyLimitOptions{
+		Limit:        StaticLimit(c.ConcurrencyLimit),
+		QueueSize:    c.ConcurrencyQueueSize,
+		QueueTimeout: time.Duration(c.ConcurrencyQueueTimeout) * time.Millisecond,
+	})
+}
+
+// NewConcurrencyLimitMiddlewareWithOptions returns a middleware configured by options.
+func NewConcurrencyLimitMiddlewareWithOptions(options ConcurrencyLimitOptions) *ConcurrencyLimitMiddleware {
+	retryAfter := options.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+
+	return &ConcurrencyLimitMiddleware{
+		limit:        options.Limit,
+		queueSize:    options.QueueSize,
+		queueTimeout: options.QueueTimeout,
+		retryAfter:   strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))),
+	}
+}
+
+// SetLimit replaces the limit. It must be called before the middleware serves requests.
+func (m *ConcurrencyLimitMiddleware) SetLimit(limit ConcurrencyLimit) {
+	m.limit = limit
+}
+
+// InFlight returns the number of requests currently in flight.
+func (m *ConcurrencyLimitMiddleware) InFlight() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inFlight
+}
+
+func (m *ConcurrencyLimitMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	inFlight, ok := m.acquire(r.Context(), route.OptionsFromContext(r.Context()).Priority)
+	if !ok {
+		rw.Header().Set("Retry-After", m.retryAfter)
+		handlers.SendServiceUnavailable(rw, nil)
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		status := 0
+		if res, ok := rw.(negroni.ResponseWriter); ok {
+			status = res.Status()
+		}
+		overloaded := status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+		m.release(time.Since(start), inFlight, overloaded)
+	}()
+
+	next.ServeHTTP(rw, r)
+}
+
+// acquire returns true if the request may proceed, together with the number of
+// requests in flight including this one.
+func (m *ConcurrencyLimitMiddleware) acquire(ctx context.Context, priority route.PriorityClass) (int, bool) {
+	class := priorityIndex(priority)
+
+	m.mu.Lock()
+	if m.inFlight < m.limit.Limit() && !m.hasWaitersFrom(class) {
+		m.inFlight++
+		inFlight := m.inFlight
+		m.mu.Unlock()
+		return inFlight, true
+	}
+
+	if m.queued >= m.queueSize && !m.shedBelow(class) {
+		m.mu.Unlock()
+		return 0, false
+	}
+
+	w := &concurrencyWaiter{ready: make(chan bool, 1)}
+	m.queues[class] = append(m.queues[class], w)
+	m.queued++
+	m.mu.Unlock()
+
+	timer := time.NewTimer(m.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case ok := <-w.ready:
+		return m.InFlight(), ok
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	removed := m.remove(class, w)
+	m.mu.Unlock()
+	if removed {
+		return 0, false
+	}
+
+	// the waiter was granted or shed concurrently
+	ok := <-w.ready
+	return m.InFlight(), ok
+}
+
+func (m *ConcurrencyLimitMiddleware) release(latency time.Duration, inFlight int, overloaded bool) {
+	m.limit.OnSample(latency, inFlight, overloaded)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight--
+	limit := m.limit.Limit()
+	for m.inFlight < limit {
+		w := m.popHighest()
+		if w == nil {
+			return
+		}
+		m.inFlight++
+		w.ready <- true
+	}
+}
+
+// hasWaitersFrom returns true if requests of class or a higher class are queued, so
+// a new request does not overtake them.
+func (m *ConcurrencyLimitMiddleware) hasWaitersFrom(class int) bool {
+	for i := class; i < priorityClasses; i++ {
+		if len(m.queues[i]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// shedBelow sheds the most recently queued request of the lowest class below class.
+func (m *ConcurrencyLimitMiddleware) shedBelow(class int) bool {
+	for i := 0; i < class; i++ {
+		if n := len(m.queues[i]); n > 0 {
+			w := m.queues[i][n-1]
+			m.queues[i] = m.queues[i][:n-1]
+			m.queued--
+			w.ready <- false
+			return true
+		}
+	}
+	return false
+}
+
+// popHighest dequeues the oldest request of the highest class.
+func (m *ConcurrencyLimitMiddleware) popHighest() *concurrencyWaiter {
+	for i := priorityClasses - 1; i >= 0; i-- {
+		if len(m.queues[i]) > 0 {
+			w := m.queues[i][0]
+			m.queues[i] = m.queues[i][1:]
+			m.queued--
+			return w
+		}
+	}
+	return nil
+}
+
+func (m *ConcurrencyLimitMiddleware) remove(class int, w *concurrencyWaiter) bool {
+	i := slices.Index(m.queues[class], w)
+	if i < 0 {
+		return false
+	}
+	m.queues[class] = slices.Delete(m.queues[class], i, i+1)
+	m.queued--
+	return true
+}
+
+func priorityIndex(priority route.PriorityClass) int {
+	return int(min(max(priority, route.PriorityLow), route.PriorityCritical) - route.PriorityLow)
+}
@@ -0,0 +1,227 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+// newBlockingConcurrencyHandler blocks requests until release is closed and signals each started request.
+func newBlockingConcurrencyHandler(m *ConcurrencyLimitMiddleware, started chan<- string, release <-chan struct{}) *negroni.Negroni {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- r.URL.Path
+		<-release
+	})
+	return n
+}
+
+func requestWithPriority(path string, priority route.PriorityClass) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	return req.WithContext(route.NewContext(req.Context(), &route.Route{
+		Options: route.Options{Priority: priority},
+	}))
+}
+
+func TestConcurrencyLimit_ShedsWithRetryAfterWhenQueueIsFull(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConcurrencyLimitMiddlewareWithOptions(ConcurrencyLimitOptions{
+		Limit:      StaticLimit(1),
+		RetryAfter: 1500 * time.Millisecond,
+	})
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	n := newBlockingConcurrencyHandler(m, started, release)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/second", nil))
+
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("2", rec.Header().Get("Retry-After"))
+	assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+
+	close(release)
+	wg.Wait()
+	assert.Equal(0, m.InFlight())
+}
+
+func TestConcurrencyLimit_QueuedRequestProceedsWhenSlotFrees(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConcurrencyLimitMiddlewareWithOptions(ConcurrencyLimitOptions{
+		Limit:        StaticLimit(1),
+		QueueSize:    1,
+		QueueTimeout: 5 * time.Second,
+	})
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	n := newBlockingConcurrencyHandler(m, started, release)
+
+	go n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	<-started
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/second", nil))
+		done <- rec.Code
+	}()
+
+	close(release)
+	assert.Equal("/second", <-started)
+	assert.Equal(http.StatusOK, <-done)
+}
+
+func TestConcurrencyLimit_QueuedRequestIsShedAfterTimeout(t *testing.T) {
+	m := NewConcurrencyLimitMiddlewareWithOptions(ConcurrencyLimitOptions{
+		Limit:        StaticLimit(1),
+		QueueSize:    1,
+		QueueTimeout: 10 * time.Millisecond,
+	})
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	defer close(release)
+	n := newBlockingConcurrencyHandler(m, started, release)
+
+	go n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	<-started
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/second", nil))
+
+	a.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestConcurrencyLimit_ShedsLowerPriorityFirst(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConcurrencyLimitMiddlewareWithOptions(ConcurrencyLimitOptions{
+		Limit:        StaticLimit(1),
+		QueueSize:    1,
+		QueueTimeout: 5 * time.Second,
+	})
+	started := make(chan string, 3)
+	release := make(chan struct{})
+	n := newBlockingConcurrencyHandler(m, started, release)
+
+	go n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	<-started
+
+	low := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, requestWithPriority("/low", route.PriorityLow))
+		low <- rec.Code
+	}()
+	assert.Eventually(func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.queued == 1
+	}, time.Second, time.Millisecond)
+
+	critical := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, requestWithPriority("/critical", route.PriorityCritical))
+		critical <- rec.Code
+	}()
+
+	assert.Equal(http.StatusServiceUnavailable, <-low)
+
+	close(release)
+	assert.Equal("/critical", <-started)
+	assert.Equal(http.StatusOK, <-critical)
+}
+
+func TestConcurrencyLimit_DequeuesHigherPriorityFirst(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConcurrencyLimitMiddlewareWithOptions(ConcurrencyLimitOptions{
+		Limit:        StaticLimit(1),
+		QueueSize:    2,
+		QueueTimeout: 5 * time.Second,
+	})
+	started := make(chan string, 3)
+	release := make(chan struct{})
+	n := newBlockingConcurrencyHandler(m, started, release)
+
+	go n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	<-started
+
+	go n.ServeHTTP(httptest.NewRecorder(), requestWithPriority("/normal", route.PriorityNormal))
+	assert.Eventually(func() bool { return queuedCount(m) == 1 }, time.Second, time.Millisecond)
+	go n.ServeHTTP(httptest.NewRecorder(), requestWithPriority("/high", route.PriorityHigh))
+	assert.Eventually(func() bool { return queuedCount(m) == 2 }, time.Second, time.Millisecond)
+
+	close(release)
+	assert.Equal("/high", <-started)
+	assert.Equal("/normal", <-started)
+}
+
+func queuedCount(m *ConcurrencyLimitMiddleware) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.queued
+}
+
+func TestAIMDLimit(t *testing.T) {
+	assert := a.New(t)
+
+	l := NewAIMDLimit(10, 2, 12, 100*time.Millisecond)
+
+	l.OnSample(time.Millisecond, 1, false)
+	assert.Equal(10, l.Limit(), "does not grow while the limit is unused")
+
+	l.OnSample(time.Millisecond, 6, false)
+	l.OnSample(time.Millisecond, 6, false)
+	l.OnSample(time.Millisecond, 6, false)
+	assert.Equal(12, l.Limit(), "grows up to the maximum")
+
+	l.OnSample(time.Second, 5, false)
+	assert.Equal(10, l.Limit(), "backs off on slow requests")
+
+	l.OnSample(time.Millisecond, 5, true)
+	assert.Equal(9, l.Limit(), "backs off on overload")
+
+	for range 50 {
+		l.OnSample(time.Second, 5, false)
+	}
+	assert.Equal(2, l.Limit(), "does not drop below the minimum")
+}
+
+func TestNewAIMDLimitWithOptions(t *testing.T) {
+	assert := a.New(t)
+
+	l, err := NewAIMDLimitWithOptions(AIMDLimitOptions{})
+	assert.NoError(err)
+	assert.Equal(1, l.Limit(), "defaults to a limit of 1")
+	l.OnSample(time.Millisecond, 1, false)
+	assert.Equal(1, l.Limit(), "does not grow above the default maximum")
+
+	l, err = NewAIMDLimitWithOptions(AIMDLimitOptions{Initial: 100, MinLimit: 2, MaxLimit: 10, BackoffRatio: 0.5})
+	assert.NoError(err)
+	assert.Equal(10, l.Limit(), "clamps the initial limit")
+	l.OnSample(0, 10, true)
+	assert.Equal(5, l.Limit())
+
+	for _, ratio := range []float64{-1, 0.4, 1, 1.5} {
+		_, err = NewAIMDLimitWithOptions(AIMDLimitOptions{BackoffRatio: ratio})
+		assert.ErrorContains(err, "backoff ratio", "ratio %v", ratio)
+	}
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimit decides how many requests may be in flight at the same time.
+type ConcurrencyLimit interface {
+	// Limit returns the current limit.
+	Limit() int
+	// OnSample is called after each request with its latency, the number of requests that
+	// were in flight when it started and whether it failed because the server is overloaded.
+	OnSample(latency time.Duration, inFlight int, overloaded bool)
+}
+
+// StaticLimit is a fixed concurrency limit.
+type StaticLimit int
+
+func (l StaticLimit) Limit() int {
+	return int(l)
+}
+
+func (l StaticLimit) OnSample(time.Duration, int, bool) {}
+
+// AIMDLimit adapts the limit to the observed latency. It increases the limit by one for
+// every request that finishes below the latency threshold while the server is busy and
+// multiplies the limit with the backoff ratio when a request is slower or fails because
+// of overload. Create it with NewAIMDLimit or NewAIMDLimitWithOptions.
+type AIMDLimit struct {
+	minLimit         int
+	maxLimit         int
+	latencyThreshold time.Duration
+	backoffRatio     float64
+
+	mu    sync.Mutex
+	limit float64
+}
+
+// AIMDLimitOptions configure an AIMDLimit.
+type AIMDLimitOptions struct {
+	// Initial is the limit to start with. It is clamped to MinLimit and MaxLimit.
+	Initial int
+	// MinLimit and MaxLimit bound the limit. MinLimit defaults to 1 and MaxLimit to MinLimit.
+	MinLimit int
+	MaxLimit int
+	// LatencyThreshold is the latency above which the limit is decreased.
+	LatencyThreshold time.Duration
+	// BackoffRatio is applied to the limit when it is decreased. It must be at least
+	// 0.5 and less than 1. Defaults to 0.9.
+	BackoffRatio float64
+}
+
+// NewAIMDLimit returns an AIMD limit starting at initial with a backoff ratio of 0.9.
+func NewAIMDLimit(initial int, minLimit int, maxLimit int, latencyThreshold time.Duration) *AIMDLimit {
+	// cannot fail, the default backoff ratio is valid
+	l, _ := NewAIMDLimitWithOptions(AIMDLimitOptions{
+		Initial:          initial,
+		MinLimit:         minLimit,
+		MaxLimit:         maxLimit,
+		LatencyThreshold: latencyThreshold,
+	})
+	return l
+}
+
+// NewAIMDLimitWithOptions returns an AIMD limit configured by options. It returns an
+// error if the backoff ratio is out of range.
+func NewAIMDLimitWithOptions(options AIMDLimitOptions) (*AIMDLimit, error) {
+	backoffRatio := options.BackoffRatio
+	if backoffRatio == 0 {
+		backoffRatio = 0.9
+	}
+	if backoffRatio < 0.5 || backoffRatio >= 1 {
+		return nil, fmt.Errorf("AIMD backoff ratio must be at least 0.5 and less than 1, got %v", backoffRatio)
+	}
+
+	minLimit := max(options.MinLimit, 1)
+	maxLimit := max(options.MaxLimit, minLimit)
+
+	return &AIMDLimit{
+		minLimit:         minLimit,
+		maxLimit:         maxLimit,
+		latencyThreshold: options.LatencyThreshold,
+		backoffRatio:     backoffRatio,
+		limit:            float64(min(max(options.Initial, minLimit), maxLimit)),
+	}, nil
+}
+
+func (l *AIMDLimit) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+func (l *AIMDLimit) OnSample(latency time.Duration, inFlight int, overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if overloaded || latency > l.latencyThreshold {
+		l.limit = max(float64(l.minLimit), l.limit*l.backoffRatio)
+		return
+	}
+
+	// only grow if the limit is actually used, otherwise an idle server would
+	// increase its limit without knowing whether it can handle the load
+	if inFlight*2 >= int(l.limit) {
+		l.limit = min(float64(l.maxLimit), l.limit+1)
+	}
+}
@@ -0,0 +1,221 @@
+package middlewares
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/urfave/negroni/v3"
+)
+
+// ConditionalRequestsOptions configure a ConditionalRequestsMiddleware.
+type ConditionalRequestsOptions struct {
+	// WeakETags generates weak instead of strong entity tags.
+	WeakETags bool
+	// MaxSize is the maximum size of response bodies that are buffered to compute an
+	// entity tag. Larger responses are sent unchanged.
+	MaxSize int
+}
+
+// ConditionalRequestsMiddleware adds an ETag to successful JSON responses of GET and HEAD
+// requests and answers them with 304 Not Modified if If-None-Match or If-Modified-Since
+// show that the client has the current representation. ETags and Last-Modified headers
+// set by handlers are kept.
+//
+// Write requests to routes with route.RequirePreconditions are answered with 428
+// Precondition Required unless they send If-Match or If-Unmodified-Since. Handlers check
+// those headers against the current resource with handlers.CheckPreconditions.
+type ConditionalRequestsMiddleware struct {
+	weak    bool
+	maxSize int
+}
+
+// NewConditionalRequestsMiddleware returns a middleware with strong entity tags that buffers
+// up to API_KIT_ETAG_MAX_SIZE bytes.
+func NewConditionalRequestsMiddleware() *ConditionalRequestsMiddleware {
+	return NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{
+		MaxSize: config.Get().EtagMaxSize,
+	})
+}
+
+// NewConditionalRequestsMiddlewareWithOptions returns a middleware configured by options.
+func NewConditionalRequestsMiddlewareWithOptions(options ConditionalRequestsOptions) *ConditionalRequestsMiddleware {
+	return &ConditionalRequestsMiddleware{
+		weak:    options.WeakETags,
+		maxSize: options.MaxSize,
+	}
+}
+
+func (m *ConditionalRequestsMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if isWriteMethod(r.Method) && route.OptionsFromContext(r.Context()).RequirePreconditions &&
+			r.Header.Get(handlers.HeaderIfMatch) == "" && r.Header.Get(handlers.HeaderIfUnmodifiedSince) == "" {
+			handlers.SendPreconditionRequired(rw, handlers.CreateErrorDetails(handlers.HeaderIfMatch, "must not be undefined"))
+			return
+		}
+		next(rw, r)
+		return
+	}
+
+	ew := &etagWriter{ResponseWriter: rw, middleware: m, request: r}
+	defer ew.close()
+
+	// later middlewares read the status from a negroni.ResponseWriter
+	next(negroni.NewResponseWriter(ew), r)
+}
+
+func isWriteMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut ||
+		method == http.MethodPatch || method == http.MethodDelete
+}
+
+// etagWriter buffers successful responses until the handler returns, so their entity tag
+// can be computed and compared with the request before the status is sent.
+type etagWriter struct {
+	http.ResponseWriter
+	middleware *ConditionalRequestsMiddleware
+	request    *http.Request
+
+	status  int
+	buf     []byte
+	decided bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	if code != http.StatusOK {
+		w.passThrough()
+	}
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > w.middleware.maxSize {
+		err := w.passThrough()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends the response without an entity tag, because handlers that flush stream
+// and need the data to reach the client right away.
+func (w *etagWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		_ = w.passThrough()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection to the handler, e.g. for WebSocket upgrades. The response
+// gets no entity tag.
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.decided = true
+	w.buf = nil
+	return conn, brw, nil
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) passThrough() error {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *etagWriter) close() {
+	if w.decided || w.status == 0 {
+		return
+	}
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	etag := header.Get(handlers.HeaderETag)
+	if etag == "" && isJsonContentType(header.Get("Content-Type")) {
+		if w.middleware.weak {
+			etag = handlers.WeakETag(w.buf)
+		} else {
+			etag = handlers.StrongETag(w.buf)
+		}
+		header.Set(handlers.HeaderETag, etag)
+	}
+
+	if w.notModified(etag, header.Get(handlers.HeaderLastModified)) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	_ = w.passThrough()
+}
+
+// notModified evaluates If-None-Match or, if it is missing, If-Modified-Since.
+func (w *etagWriter) notModified(etag string, lastModified string) bool {
+	if ifNoneMatch := w.request.Header.Values(handlers.HeaderIfNoneMatch); len(ifNoneMatch) > 0 {
+		return (etag != "" && isWildcardList(ifNoneMatch)) || handlers.MatchETag(ifNoneMatch, etag, true)
+	}
+
+	ifModifiedSince := w.request.Header.Get(handlers.HeaderIfModifiedSince)
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+func isWildcardList(values []string) bool {
+	return len(values) == 1 && strings.TrimSpace(values[0]) == "*"
+}
+
+// isJsonContentType reports whether contentType is application/json or a +json media type.
+func isJsonContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
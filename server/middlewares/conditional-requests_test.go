@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveConditional(m *ConditionalRequestsMiddleware, req *http.Request, fn http.HandlerFunc) *httptest.ResponseRecorder {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(fn)
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+func sendUser(w http.ResponseWriter, r *http.Request) {
+	handlers.SendStructAsJson(w, map[string]string{"name": "a"})
+}
+
+func TestConditionalRequestsMiddleware_AddsETagToJson(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 1024})
+	rec := serveConditional(m, httptest.NewRequest(http.MethodGet, "/", nil), sendUser)
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(handlers.StrongETag([]byte("{\"name\":\"a\"}\n")), rec.Header().Get("ETag"))
+	assert.Equal("{\"name\":\"a\"}\n", rec.Body.String())
+
+	m = NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 1024, WeakETags: true})
+	rec = serveConditional(m, httptest.NewRequest(http.MethodGet, "/", nil), sendUser)
+	assert.True(strings.HasPrefix(rec.Header().Get("ETag"), `W/"`))
+}
+
+func TestConditionalRequestsMiddleware_RespondsNotModified(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 1024})
+	etag := handlers.StrongETag([]byte("{\"name\":\"a\"}\n"))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set("If-None-Match", `"other", W/`+etag)
+		rec := serveConditional(m, req, sendUser)
+
+		assert.Equal(http.StatusNotModified, rec.Code)
+		assert.Equal(etag, rec.Header().Get("ETag"))
+		assert.Empty(rec.Header().Get("Content-Type"))
+		assert.Empty(rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"other"`)
+	rec := serveConditional(m, req, sendUser)
+	assert.Equal(http.StatusOK, rec.Code)
+}
+
+func TestConditionalRequestsMiddleware_IfModifiedSince(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 1024})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Tue, 02 Jan 2024 03:04:05 GMT")
+		handlers.SendText(w, "text")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-Modified-Since", "Tue, 02 Jan 2024 03:04:05 GMT")
+	rec := serveConditional(m, req, handler)
+	assert.Equal(http.StatusNotModified, rec.Code)
+	assert.Empty(rec.Header().Get("ETag"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-Modified-Since", "Mon, 01 Jan 2024 03:04:05 GMT")
+	rec = serveConditional(m, req, handler)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("text", rec.Body.String())
+}
+
+func TestConditionalRequestsMiddleware_PassesThrough(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 8})
+	for name, handler := range map[string]http.HandlerFunc{
+		"error": func(w http.ResponseWriter, r *http.Request) {
+			handlers.SendNotFound(w, nil)
+		},
+		"too large": sendUser,
+		"flushed": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("{}"))
+			w.(http.Flusher).Flush()
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("If-None-Match", "*")
+			rec := serveConditional(m, req, handler)
+
+			assert.NotEqual(http.StatusNotModified, rec.Code)
+			assert.Empty(rec.Header().Get("ETag"))
+			assert.NotEmpty(rec.Body.String())
+		})
+	}
+}
+
+func TestConditionalRequestsMiddleware_RequiresPreconditions(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 1024})
+	withRoute := func(req *http.Request) *http.Request {
+		return req.WithContext(route.NewContext(req.Context(), &route.Route{
+			Pattern: "PUT /users/{id}",
+			Options: route.Options{}.Apply(route.RequirePreconditions()),
+		}))
+	}
+	noContent := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	rec := serveConditional(m, withRoute(httptest.NewRequest(http.MethodPut, "/users/1", nil)), noContent)
+	assert.Equal(http.StatusPreconditionRequired, rec.Code)
+	assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+
+	req := withRoute(httptest.NewRequest(http.MethodPut, "/users/1", nil))
+	req.Header.Set("If-Match", `"v1"`)
+	rec = serveConditional(m, req, noContent)
+	assert.Equal(http.StatusNoContent, rec.Code)
+
+	rec = serveConditional(m, httptest.NewRequest(http.MethodPut, "/users/1", nil), noContent)
+	assert.Equal(http.StatusNoContent, rec.Code)
+}
+
+func TestConditionalRequestsMiddleware_SupportsHijack(t *testing.T) {
+	a.Equal(t, "HTTP/1.1 101 Switching Protocols", serveHijack(t, NewConditionalRequestsMiddlewareWithOptions(ConditionalRequestsOptions{MaxSize: 1024})))
+}
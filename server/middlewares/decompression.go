@@ -0,0 +1,223 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/handlers"
+)
+
+// ratioCheckMinSize is the decompressed size from which the compression ratio is checked,
+// because small bodies of repetitive JSON easily exceed any sensible ratio.
+const ratioCheckMinSize = 64 * 1024
+
+// Decoder returns a reader that decompresses r.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// DecompressionOptions configure a DecompressionMiddleware.
+type DecompressionOptions struct {
+	// Decoders maps content codings to decoders. Defaults to gzip, x-gzip and deflate.
+	Decoders map[string]Decoder
+	// MaxSize is the maximum size of the decompressed body in bytes.
+	MaxSize int64
+	// MaxRatio is the maximum ratio of decompressed to compressed bytes. Zero disables
+	// the check.
+	MaxRatio int64
+}
+
+// DecompressionMiddleware decompresses request bodies with a Content-Encoding, so handlers
+// read the plain body. Reading more than MaxSize decompressed bytes or exceeding MaxRatio
+// fails with *http.MaxBytesError, which ValidatingHandler answers with 413 Payload Too
+// Large. If the handler does not respond, the middleware sends SendPayloadTooLarge.
+// Unknown or stacked encodings are rejected with 415 Unsupported Media Type and malformed
+// bodies with 400 Bad Request.
+type DecompressionMiddleware struct {
+	decoders map[string]Decoder
+	maxSize  int64
+	maxRatio int64
+}
+
+// NewDecompressionMiddleware returns a middleware for gzip and deflate with the limits set
+// by API_KIT_MAX_DECOMPRESSED_BODY_SIZE and API_KIT_MAX_DECOMPRESSION_RATIO.
+func NewDecompressionMiddleware() *DecompressionMiddleware {
+	c := config.Get()
+	return NewDecompressionMiddlewareWithOptions(DecompressionOptions{
+		MaxSize:  int64(c.MaxDecompressedBodySize),
+		MaxRatio: int64(c.MaxDecompressionRatio),
+	})
+}
+
+// NewDecompressionMiddlewareWithOptions returns a middleware configured by options.
+func NewDecompressionMiddlewareWithOptions(options DecompressionOptions) *DecompressionMiddleware {
+	m := &DecompressionMiddleware{
+		decoders: map[string]Decoder{},
+		maxSize:  options.MaxSize,
+		maxRatio: options.MaxRatio,
+	}
+
+	decoders := options.Decoders
+	if len(decoders) == 0 {
+		decoders = map[string]Decoder{
+			"gzip":    newGzipDecoder,
+			"x-gzip":  newGzipDecoder,
+			"deflate": zlib.NewReader,
+		}
+	}
+	for coding, d := range decoders {
+		m.decoders[strings.ToLower(coding)] = d
+	}
+	return m
+}
+
+func newGzipDecoder(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (m *DecompressionMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var codings []string
+	for _, value := range r.Header.Values("Content-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" && coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	if len(codings) == 0 || r.Body == nil || r.Body == http.NoBody {
+		next(rw, r)
+		return
+	}
+
+	decoder, ok := m.decoders[codings[0]]
+	if len(codings) > 1 || !ok {
+		rw.Header().Set("Accept-Encoding", m.acceptEncoding())
+		handlers.SendUnsupportedMediaType(rw, nil)
+		return
+	}
+
+	raw := &countingReader{reader: r.Body}
+	decoded, err := decoder(raw)
+	if err != nil {
+		handlers.SendBadRequest(rw, nil)
+		return
+	}
+
+	body := &decompressingReader{
+		raw:      raw,
+		decoded:  decoded,
+		body:     r.Body,
+		maxSize:  m.maxSize,
+		maxRatio: m.maxRatio,
+	}
+
+	r2 := r.Clone(r.Context())
+	r2.Body = body
+	r2.ContentLength = -1
+	r2.Header.Del("Content-Encoding")
+	r2.Header.Del("Content-Length")
+
+	// track writes locally, rw is not a negroni.ResponseWriter behind the timeout
+	// middleware or in std chains
+	tw := &writeTrackingWriter{ResponseWriter: rw}
+	next(tw, r2)
+
+	if body.exceeded && !tw.written {
+		handlers.SendPayloadTooLarge(rw, nil)
+	}
+}
+
+func (m *DecompressionMiddleware) acceptEncoding() string {
+	codings := make([]string, 0, len(m.decoders))
+	for coding := range m.decoders {
+		codings = append(codings, coding)
+	}
+	slices.Sort(codings)
+	return strings.Join(codings, ", ")
+}
+
+// writeTrackingWriter records whether the handler responded.
+type writeTrackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *writeTrackingWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writeTrackingWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *writeTrackingWriter) Flush() {
+	w.written = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *writeTrackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type decompressingReader struct {
+	raw      *countingReader
+	decoded  io.ReadCloser
+	body     io.ReadCloser
+	n        int64
+	maxSize  int64
+	maxRatio int64
+	exceeded bool
+	err      error
+}
+
+func (d *decompressingReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.maxSize > 0 && int64(len(p)) > d.maxSize-d.n+1 {
+		// read at most one byte more than allowed to detect the overflow
+		p = p[:d.maxSize-d.n+1]
+	}
+
+	n, err := d.decoded.Read(p)
+	d.n += int64(n)
+
+	if d.maxSize > 0 && d.n > d.maxSize {
+		d.fail(d.maxSize)
+		return n - int(d.n-d.maxSize), d.err
+	}
+	if d.maxRatio > 0 && d.n > ratioCheckMinSize && d.n > d.raw.n*d.maxRatio {
+		d.fail(d.raw.n * d.maxRatio)
+		return n, d.err
+	}
+	return n, err
+}
+
+func (d *decompressingReader) fail(limit int64) {
+	d.exceeded = true
+	d.err = &http.MaxBytesError{Limit: limit}
+}
+
+func (d *decompressingReader) Close() error {
+	_ = d.decoded.Close()
+	return d.body.Close()
+}
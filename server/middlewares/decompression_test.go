@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func gzipBytes(s string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, _ = gw.Write([]byte(s))
+	_ = gw.Close()
+	return buf.Bytes()
+}
+
+func serveDecompression(m *DecompressionMiddleware, encoding string, body []byte, fn http.HandlerFunc) *httptest.ResponseRecorder {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(fn)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", encoding)
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func TestDecompressionMiddleware_DecodesGzipAndDeflate(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewDecompressionMiddlewareWithOptions(DecompressionOptions{MaxSize: 1024})
+	var encoding string
+	rec := serveDecompression(m, "gzip", gzipBytes(`{"name":"a"}`), func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		readBody(w, r)
+	})
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(`{"name":"a"}`, rec.Body.String())
+	assert.Empty(encoding)
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"name":"b"}`))
+	_ = zw.Close()
+	rec = serveDecompression(m, "deflate", buf.Bytes(), readBody)
+	assert.Equal(`{"name":"b"}`, rec.Body.String())
+}
+
+func TestDecompressionMiddleware_LimitsDecompressedSize(t *testing.T) {
+	m := NewDecompressionMiddlewareWithOptions(DecompressionOptions{MaxSize: 100})
+
+	rec := serveDecompression(m, "gzip", gzipBytes(strings.Repeat("a", 101)), func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+
+	a.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestDecompressionMiddleware_LimitsRatio(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewDecompressionMiddlewareWithOptions(DecompressionOptions{MaxSize: 100 << 20, MaxRatio: 100})
+
+	// ValidatingHandler answers the limit itself
+	rec := serveDecompression(m, "gzip", gzipBytes(`{"name":"`+strings.Repeat("a", 1<<20)+`"}`),
+		handlers.ValidatingHandler(func(w http.ResponseWriter, r *http.Request, p *testPayload) {
+			t.Error("handler should not be called")
+		}))
+	assert.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+}
+
+func TestDecompressionMiddleware_RespondsOnceWithoutNegroniWriter(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewDecompressionMiddlewareWithOptions(DecompressionOptions{MaxSize: 100})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(strings.Repeat("a", 101))))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	m.ServeHTTP(rec, req, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		_, _ = w.Write([]byte("handled"))
+	})
+	assert.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal("handled", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(strings.Repeat("a", 101))))
+	req.Header.Set("Content-Encoding", "gzip")
+	m.ServeHTTP(rec, req, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	})
+	assert.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+}
+
+func TestDecompressionMiddleware_RejectsUnknownAndMalformedBodies(t *testing.T) {
+	assert := a.New(t)
+
+	m := NewDecompressionMiddlewareWithOptions(DecompressionOptions{})
+	fail := func(w http.ResponseWriter, r *http.Request) { t.Error("handler should not be called") }
+
+	rec := serveDecompression(m, "br", []byte("x"), fail)
+	assert.Equal(http.StatusUnsupportedMediaType, rec.Code)
+	assert.Equal("deflate, gzip, x-gzip", rec.Header().Get("Accept-Encoding"))
+
+	rec = serveDecompression(m, "gzip, gzip", gzipBytes("x"), fail)
+	assert.Equal(http.StatusUnsupportedMediaType, rec.Code)
+
+	rec = serveDecompression(m, "gzip", []byte("not gzip"), fail)
+	assert.Equal(http.StatusBadRequest, rec.Code)
+}
+
+type testPayload struct {
+	Name string `json:"name" validate:"required"`
+}
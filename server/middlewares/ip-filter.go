@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/clientip"
+	"github.com/stfsy/go-api-kit/server/handlers"
+)
+
+// IPFilterMiddleware responds with 403 Forbidden to clients whose IP address is in a
+// denied range or, if allowed ranges are set, not in an allowed range. The address is
+// read with clientip.FromRequest, so it honours the TrustedProxiesMiddleware.
+type IPFilterMiddleware struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewIPFilterMiddleware returns a middleware with the ranges set by API_KIT_IP_ALLOW_LIST
+// and API_KIT_IP_DENY_LIST. It returns an error if a range is invalid.
+func NewIPFilterMiddleware() (*IPFilterMiddleware, error) {
+	c := config.Get()
+	return NewIPFilterMiddlewareWithCIDRs(c.IpAllowList, c.IpDenyList)
+}
+
+// NewIPFilterMiddlewareWithCIDRs returns a middleware with the given allowed and denied
+// CIDR ranges, e.g. 10.0.0.0/8. Single addresses are accepted, too.
+func NewIPFilterMiddlewareWithCIDRs(allow []string, deny []string) (*IPFilterMiddleware, error) {
+	allowPrefixes, err := parsePrefixes(allow)
+	if err != nil {
+		return nil, err
+	}
+	denyPrefixes, err := parsePrefixes(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &IPFilterMiddleware{allow: allowPrefixes, deny: denyPrefixes}, nil
+}
+
+func (m *IPFilterMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !m.allowed(clientip.FromRequest(r).IP) {
+		handlers.SendForbidden(rw, nil)
+		return
+	}
+	next(rw, r)
+}
+
+func (m *IPFilterMiddleware) allowed(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return len(m.allow) == 0 && len(m.deny) == 0
+	}
+	if containsAddr(m.deny, addr) {
+		return false
+	}
+	return len(m.allow) == 0 || containsAddr(m.allow, addr)
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveIPFilter(m *IPFilterMiddleware, remoteAddr string) int {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestIPFilterMiddleware_AllowList(t *testing.T) {
+	assert := a.New(t)
+
+	m, err := NewIPFilterMiddlewareWithCIDRs([]string{"10.0.0.0/8"}, []string{"10.0.0.66"})
+	assert.NoError(err)
+
+	assert.Equal(http.StatusOK, serveIPFilter(m, "10.1.2.3:1234"))
+	assert.Equal(http.StatusForbidden, serveIPFilter(m, "10.0.0.66:1234"))
+	assert.Equal(http.StatusForbidden, serveIPFilter(m, "192.0.2.1:1234"))
+}
+
+func TestIPFilterMiddleware_DenyList(t *testing.T) {
+	assert := a.New(t)
+
+	m, err := NewIPFilterMiddlewareWithCIDRs(nil, []string{"2001:db8::/32"})
+	assert.NoError(err)
+
+	assert.Equal(http.StatusForbidden, serveIPFilter(m, "[2001:db8::1]:1234"))
+	assert.Equal(http.StatusOK, serveIPFilter(m, "192.0.2.1:1234"))
+}
+
+func TestIPFilterMiddleware_UsesTrustedProxies(t *testing.T) {
+	filter, _ := NewIPFilterMiddlewareWithCIDRs(nil, []string{"198.51.100.1"})
+	proxies, _ := NewTrustedProxiesMiddlewareWithCIDRs("10.0.0.0/8")
+
+	n := negroni.New(proxies, filter)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+
+	a.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/metrics"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/urfave/negroni/v3"
+)
+
+// MetricsMiddleware records the number, latency and response size of requests labelled by
+// method, route pattern and status class, and the number of requests in flight. Requests
+// without a matching pattern are recorded with the route "unmatched" and unknown methods
+// as "OTHER", so clients cannot create an unbounded number of series.
+type MetricsMiddleware struct {
+	requests *metrics.Counter
+	inFlight *metrics.Gauge
+	duration *metrics.Histogram
+	size     *metrics.Histogram
+}
+
+// NewMetricsMiddleware registers the request metrics in registry. It returns an error if
+// registry has metrics of the same names but other types or labels.
+func NewMetricsMiddleware(registry *metrics.Registry) (*MetricsMiddleware, error) {
+	m := &MetricsMiddleware{}
+	var err error
+
+	m.requests, err = registry.Counter("http_server_requests_total",
+		"Number of HTTP requests.", "method", "route", "status")
+	if err != nil {
+		return nil, err
+	}
+	m.inFlight, err = registry.Gauge("http_server_requests_in_flight",
+		"Number of HTTP requests currently being served.")
+	if err != nil {
+		return nil, err
+	}
+	m.duration, err = registry.Histogram("http_server_request_duration_seconds",
+		"Duration of HTTP requests in seconds.", metrics.DefaultDurationBuckets, "method", "route", "status")
+	if err != nil {
+		return nil, err
+	}
+	m.size, err = registry.Histogram("http_server_response_size_bytes",
+		"Size of HTTP response bodies in bytes.", metrics.DefaultSizeBuckets, "method", "route", "status")
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *MetricsMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	m.inFlight.Inc()
+	defer m.inFlight.Dec()
+
+	next(rw, r)
+
+	status, size := 0, 0
+	if res, ok := rw.(negroni.ResponseWriter); ok {
+		status = res.Status()
+		size = res.Size()
+	}
+
+	method := metricsMethod(r.Method)
+	routeLabel := metricsRoute(r)
+	statusClass := fmt.Sprintf("%dxx", status/100)
+
+	m.requests.Inc(method, routeLabel, statusClass)
+	m.duration.Observe(time.Since(start).Seconds(), method, routeLabel, statusClass)
+	m.size.Observe(float64(size), method, routeLabel, statusClass)
+}
+
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// metricsRoute returns the matched pattern without its method, which is a label of its own.
+func metricsRoute(r *http.Request) string {
+	resolved := route.FromContext(r.Context())
+	if resolved == nil || resolved.Pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(resolved.Pattern, " "); ok {
+		return strings.TrimLeft(path, " \t")
+	}
+	return resolved.Pattern
+}
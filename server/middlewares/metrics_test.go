@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/metrics"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func TestMetricsMiddleware_RecordsRequests(t *testing.T) {
+	assert := a.New(t)
+
+	registry := metrics.NewRegistry()
+	m, err := NewMetricsMiddleware(registry)
+	assert.NoError(err)
+
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{Pattern: "POST /users"}))
+	n.ServeHTTP(httptest.NewRecorder(), req)
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/unknown", nil))
+
+	var buf bytes.Buffer
+	assert.NoError(registry.Write(&buf))
+	assert.Contains(buf.String(), `http_server_requests_total{method="POST",route="/users",status="2xx"} 1`)
+	assert.Contains(buf.String(), `http_server_requests_total{method="OTHER",route="unmatched",status="2xx"} 1`)
+	assert.Contains(buf.String(), `http_server_response_size_bytes_sum{method="POST",route="/users",status="2xx"} 5`)
+	assert.Contains(buf.String(), `http_server_request_duration_seconds_count{method="POST",route="/users",status="2xx"} 1`)
+	assert.Contains(buf.String(), "http_server_requests_in_flight 0")
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/clientip"
+)
+
+// KeyFunc returns the key that a request is counted for.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP counts requests per IP address of the client, as resolved by clientip.FromRequest.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + clientip.IP(r)
+}
+
+// KeyByHeader counts requests per value of the header, e.g. an API key. Requests
+// without the header are counted per IP address.
+//
+// The header is not authenticated by the rate limit middleware. It must be verified
+// upstream, e.g. by an API gateway, or clients get a fresh limit by sending a new value
+// with every request. The value is hashed, so the key has a fixed length and secrets
+// are not kept in the store.
+func KeyByHeader(header string) KeyFunc {
+	return func(r *http.Request) string {
+		value := r.Header.Get(header)
+		if value == "" {
+			return KeyByIP(r)
+		}
+		sum := sha256.Sum256([]byte(value))
+		return "header:" + hex.EncodeToString(sum[:])
+	}
+}
+
+// KeyByPrincipal counts requests per principal returned by principal, e.g. the user ID
+// stored in the context by an authentication middleware. Requests without a principal
+// are counted per IP address.
+func KeyByPrincipal(principal func(r *http.Request) string) KeyFunc {
+	return func(r *http.Request) string {
+		value := principal(r)
+		if value == "" {
+			return KeyByIP(r)
+		}
+		return "principal:" + value
+	}
+}
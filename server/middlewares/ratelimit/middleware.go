@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+var logger = utils.NewLogger("rate-limit-middleware")
+
+// DefaultPolicyName is the name of the policy created from API_KIT_RATE_LIMIT.
+const DefaultPolicyName = "default"
+
+// Config configures a Middleware.
+type Config struct {
+	// Policies are the available policies. Routes select a policy by name with the
+	// route.RateLimitPolicy option.
+	Policies []Policy
+	// DefaultPolicy is the name of the policy for routes without a policy. If empty,
+	// such routes are not limited.
+	DefaultPolicy string
+	// Store counts the requests. Defaults to a MemoryStore.
+	Store Store
+}
+
+// Middleware responds with 429 Too Many Requests if a client exceeds the policy of the
+// matched route. It sends the RateLimit-Policy and RateLimit headers of the IETF draft
+// "RateLimit header fields for HTTP" on every limited response and Retry-After on 429.
+// If the store fails, requests are allowed.
+type Middleware struct {
+	policies      map[string]Policy
+	defaultPolicy string
+	store         Store
+}
+
+// NewMiddleware returns a middleware with a token bucket policy per client IP address
+// configured by API_KIT_RATE_LIMIT and API_KIT_RATE_LIMIT_WINDOW. It returns an error if
+// the limit is set without a window.
+func NewMiddleware() (*Middleware, error) {
+	c := config.Get()
+	return NewMiddlewareWithConfig(Config{
+		Policies: []Policy{{
+			Name:   DefaultPolicyName,
+			Limit:  c.RateLimit,
+			Window: time.Duration(c.RateLimitWindow) * time.Second,
+		}},
+		DefaultPolicy: DefaultPolicyName,
+	})
+}
+
+// NewMiddlewareWithConfig returns a middleware configured by c. It returns an error if a
+// policy has no window or the default policy does not exist.
+func NewMiddlewareWithConfig(c Config) (*Middleware, error) {
+	m := &Middleware{
+		policies:      make(map[string]Policy, len(c.Policies)),
+		defaultPolicy: c.DefaultPolicy,
+		store:         c.Store,
+	}
+	if m.store == nil {
+		m.store = NewMemoryStore()
+	}
+
+	for _, p := range c.Policies {
+		if p.Limit > 0 && p.Window <= 0 {
+			return nil, fmt.Errorf("rate limit policy %s requires a window", p.Name)
+		}
+		if p.Key == nil {
+			p.Key = KeyByIP
+		}
+		m.policies[p.Name] = p
+	}
+
+	if _, ok := m.policies[c.DefaultPolicy]; c.DefaultPolicy != "" && !ok {
+		return nil, fmt.Errorf("rate limit policy %s does not exist", c.DefaultPolicy)
+	}
+
+	return m, nil
+}
+
+func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	name := route.OptionsFromContext(r.Context()).RateLimitPolicy
+	if name == "" {
+		name = m.defaultPolicy
+	}
+
+	policy, ok := m.policies[name]
+	if !ok {
+		if name != "" {
+			logger.Error(fmt.Sprintf("Rate limit policy %s does not exist", name))
+		}
+		next.ServeHTTP(rw, r)
+		return
+	}
+	if policy.Limit <= 0 {
+		next.ServeHTTP(rw, r)
+		return
+	}
+
+	result, err := m.store.Take(r.Context(), policy.Name+":"+policy.Key(r), policy, time.Now())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to count request for rate limit policy %s: %s", policy.Name, err.Error()))
+		next.ServeHTTP(rw, r)
+		return
+	}
+
+	reset := strconv.Itoa(ceilSeconds(result.Reset))
+	headers := rw.Header()
+	headers.Set("RateLimit-Policy", fmt.Sprintf("%q;q=%d;w=%d", policy.Name, policy.Limit, ceilSeconds(policy.Window)))
+	headers.Set("RateLimit", fmt.Sprintf("%q;r=%d;t=%s", policy.Name, result.Remaining, reset))
+
+	if !result.Allowed {
+		headers.Set("Retry-After", reset)
+		handlers.SendTooManyRequests(rw, nil)
+		return
+	}
+
+	next.ServeHTTP(rw, r)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
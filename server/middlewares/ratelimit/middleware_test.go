@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/clientip"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func newTestHandler(t *testing.T, c Config) *negroni.Negroni {
+	m, err := NewMiddlewareWithConfig(c)
+	a.NoError(t, err)
+
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	return n
+}
+
+func serveWithPolicy(n http.Handler, policy string, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if policy != "" {
+		req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+			Options: route.Options{RateLimitPolicy: policy},
+		}))
+	}
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_RespondsWithTooManyRequestsAndHeaders(t *testing.T) {
+	assert := a.New(t)
+
+	n := newTestHandler(t, Config{
+		Policies:      []Policy{{Name: "default", Limit: 1, Window: time.Minute}},
+		DefaultPolicy: "default",
+	})
+
+	rec := serveWithPolicy(n, "", "192.0.2.1:1234")
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal(`"default";q=1;w=60`, rec.Header().Get("RateLimit-Policy"))
+	assert.Equal(`"default";r=0;t=60`, rec.Header().Get("RateLimit"))
+
+	rec = serveWithPolicy(n, "", "192.0.2.1:4321")
+	assert.Equal(http.StatusTooManyRequests, rec.Code)
+	assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+	assert.Equal("60", rec.Header().Get("Retry-After"))
+
+	rec = serveWithPolicy(n, "", "192.0.2.2:1234")
+	assert.Equal(http.StatusOK, rec.Code, "other clients are not limited")
+}
+
+func TestMiddleware_UsesRoutePolicy(t *testing.T) {
+	assert := a.New(t)
+
+	n := newTestHandler(t, Config{
+		Policies: []Policy{
+			{Name: "default", Limit: 100, Window: time.Minute},
+			{Name: "login", Limit: 1, Window: time.Minute, Algorithm: SlidingWindow},
+		},
+		DefaultPolicy: "default",
+	})
+
+	assert.Equal(http.StatusOK, serveWithPolicy(n, "login", "192.0.2.1:1").Code)
+	assert.Equal(http.StatusTooManyRequests, serveWithPolicy(n, "login", "192.0.2.1:1").Code)
+	assert.Equal(http.StatusOK, serveWithPolicy(n, "", "192.0.2.1:1").Code, "policies are counted separately")
+}
+
+func TestMiddleware_DoesNotLimitWithoutPolicy(t *testing.T) {
+	n := newTestHandler(t, Config{
+		Policies: []Policy{{Name: "login", Limit: 1, Window: time.Minute}},
+	})
+
+	rec := serveWithPolicy(n, "", "192.0.2.1:1")
+	rec = serveWithPolicy(n, "", "192.0.2.1:1")
+
+	a.Equal(t, http.StatusOK, rec.Code)
+	a.Empty(t, rec.Header().Get("RateLimit"))
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Policy, time.Time) (Result, error) {
+	return Result{}, errors.New("unavailable")
+}
+
+func TestMiddleware_AllowsRequestsIfStoreFails(t *testing.T) {
+	n := newTestHandler(t, Config{
+		Policies:      []Policy{{Name: "default", Limit: 1, Window: time.Minute}},
+		DefaultPolicy: "default",
+		Store:         failingStore{},
+	})
+
+	a.Equal(t, http.StatusOK, serveWithPolicy(n, "", "192.0.2.1:1").Code)
+}
+
+func TestNewMiddlewareWithConfig_ValidatesPolicies(t *testing.T) {
+	_, err := NewMiddlewareWithConfig(Config{Policies: []Policy{{Name: "p", Limit: 1}}})
+	a.EqualError(t, err, "rate limit policy p requires a window")
+
+	_, err = NewMiddlewareWithConfig(Config{DefaultPolicy: "missing"})
+	a.EqualError(t, err, "rate limit policy missing does not exist")
+}
+
+func TestKeyFuncs(t *testing.T) {
+	assert := a.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8::1]:1234"
+	assert.Equal("ip:2001:db8::1", KeyByIP(req))
+	assert.Equal("ip:2001:db8::1", KeyByHeader("X-Api-Key")(req))
+
+	// the address resolved by the trusted proxies middleware takes precedence
+	proxied := req.WithContext(clientip.NewContext(req.Context(), clientip.Client{IP: netip.MustParseAddr("192.0.2.7")}))
+	assert.Equal("ip:192.0.2.7", KeyByIP(proxied))
+
+	req.Header.Set("X-Api-Key", "secret")
+	key := KeyByHeader("X-Api-Key")(req)
+	assert.Equal("header:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", key)
+	assert.NotContains(key, "secret")
+
+	principal := KeyByPrincipal(func(r *http.Request) string { return "user-1" })
+	assert.Equal("principal:user-1", principal(req))
+}
@@ -0,0 +1,44 @@
+// Package ratelimit limits the number of requests per client with token bucket or
+// sliding window policies and reports the limits in RateLimit-Policy and RateLimit headers.
+package ratelimit
+
+import (
+	"time"
+)
+
+// Algorithm decides how requests are counted.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit requests and refills Limit tokens per Window.
+	TokenBucket Algorithm = iota
+	// SlidingWindow allows Limit requests in any Window, approximated from the counts of
+	// the current and the previous fixed window.
+	SlidingWindow
+)
+
+// Policy is a named rate limit.
+type Policy struct {
+	// Name identifies the policy in the RateLimit-Policy and RateLimit headers and is used
+	// by routes to select the policy.
+	Name string
+	// Limit is the number of requests allowed per Window. Zero or less disables the limit.
+	Limit int
+	// Window is the time in which Limit requests are allowed.
+	Window time.Duration
+	// Algorithm counts the requests. Defaults to TokenBucket.
+	Algorithm Algorithm
+	// Key returns the client a request is counted for. Defaults to KeyByIP.
+	Key KeyFunc
+}
+
+// Result is the outcome of counting a request.
+type Result struct {
+	// Allowed is true if the request is within the limit.
+	Allowed bool
+	// Remaining is the number of requests the client can still make.
+	Remaining int
+	// Reset is the time until the quota is restored, or until the next request is allowed
+	// if the request was not allowed.
+	Reset time.Duration
+}
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store counts requests. Implementations backed by a shared database allow limits to
+// be enforced across several instances of a service.
+type Store interface {
+	// Take counts a request of key under policy at now.
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+const memoryStoreSweepInterval = time.Minute
+
+// DefaultMemoryStoreMaxKeys is the number of keys a store created by NewMemoryStore
+// keeps counters for.
+const DefaultMemoryStoreMaxKeys = 100_000
+
+// MemoryStore keeps counters in memory. Expired counters are removed periodically. If
+// the store is full, a counter is evicted for each new key, so the memory stays bounded
+// even if clients send a new key with every request.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	maxKeys   int
+	nextSweep time.Time
+}
+
+type memoryBucket struct {
+	// token bucket
+	tokens float64
+	last   time.Time
+	// sliding window
+	windowStart time.Time
+	current     int
+	previous    int
+	expires     time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store for up to DefaultMemoryStoreMaxKeys keys.
+func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreWithMaxKeys(DefaultMemoryStoreMaxKeys)
+}
+
+// NewMemoryStoreWithMaxKeys returns an empty in-memory store for up to maxKeys keys.
+// A value of 0 or less uses DefaultMemoryStoreMaxKeys.
+func NewMemoryStoreWithMaxKeys(maxKeys int) *MemoryStore {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMemoryStoreMaxKeys
+	}
+	return &MemoryStore{
+		buckets: map[string]*memoryBucket{},
+		maxKeys: maxKeys,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= s.maxKeys {
+			s.evict(now)
+		}
+		b = &memoryBucket{tokens: float64(policy.Limit), last: now}
+		s.buckets[key] = b
+	}
+
+	if policy.Algorithm == SlidingWindow {
+		return b.takeSlidingWindow(policy, now), nil
+	}
+	return b.takeTokenBucket(policy, now), nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, key)
+		}
+	}
+	s.nextSweep = now.Add(memoryStoreSweepInterval)
+}
+
+// memoryStoreEvictionSamples is the number of counters evict looks at for an expired one.
+const memoryStoreEvictionSamples = 8
+
+// evict removes one counter, preferring an expired one among a few samples. Map
+// iteration starts at a random key, so clients cannot choose which counter is removed.
+func (s *MemoryStore) evict(now time.Time) {
+	var victim string
+	samples := 0
+	for key, b := range s.buckets {
+		victim = key
+		samples++
+		if now.After(b.expires) || samples == memoryStoreEvictionSamples {
+			break
+		}
+	}
+	delete(s.buckets, victim)
+}
+
+func (b *memoryBucket) takeTokenBucket(policy Policy, now time.Time) Result {
+	limit := float64(policy.Limit)
+	rate := limit / policy.Window.Seconds() // tokens per second
+
+	elapsed := max(now.Sub(b.last).Seconds(), 0)
+	b.tokens = min(limit, b.tokens+elapsed*rate)
+	b.last = now
+	// a full bucket is the same as no bucket
+	b.expires = now.Add(policy.Window)
+
+	if b.tokens < 1 {
+		return Result{
+			Allowed:   false,
+			Remaining: 0,
+			Reset:     secondsToDuration((1 - b.tokens) / rate),
+		}
+	}
+
+	b.tokens--
+	return Result{
+		Allowed:   true,
+		Remaining: int(b.tokens),
+		Reset:     secondsToDuration((limit - b.tokens) / rate),
+	}
+}
+
+func (b *memoryBucket) takeSlidingWindow(policy Policy, now time.Time) Result {
+	windowStart := now.Truncate(policy.Window)
+	if !windowStart.Equal(b.windowStart) {
+		if windowStart.Sub(b.windowStart) == policy.Window {
+			b.previous = b.current
+		} else {
+			b.previous = 0
+		}
+		b.current = 0
+		b.windowStart = windowStart
+	}
+	b.expires = windowStart.Add(2 * policy.Window)
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(policy.Window)
+	estimated := int(math.Ceil(float64(b.previous)*weight)) + b.current
+	untilWindowEnd := policy.Window - elapsed
+
+	if estimated >= policy.Limit {
+		return Result{
+			Allowed:   false,
+			Remaining: 0,
+			Reset:     untilWindowEnd,
+		}
+	}
+
+	b.current++
+	return Result{
+		Allowed:   true,
+		Remaining: policy.Limit - estimated - 1,
+		Reset:     untilWindowEnd,
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
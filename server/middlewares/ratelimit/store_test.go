@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	assert := a.New(t)
+
+	s := NewMemoryStore()
+	policy := Policy{Name: "p", Limit: 2, Window: 10 * time.Second}
+	now := time.Now()
+
+	r, _ := s.Take(context.Background(), "k", policy, now)
+	assert.Equal(Result{Allowed: true, Remaining: 1, Reset: 5 * time.Second}, r)
+
+	r, _ = s.Take(context.Background(), "k", policy, now)
+	assert.Equal(Result{Allowed: true, Remaining: 0, Reset: 10 * time.Second}, r)
+
+	r, _ = s.Take(context.Background(), "k", policy, now)
+	assert.False(r.Allowed)
+	assert.Equal(5*time.Second, r.Reset)
+
+	// one token is refilled after half the window
+	r, _ = s.Take(context.Background(), "k", policy, now.Add(5*time.Second))
+	assert.True(r.Allowed)
+
+	r, _ = s.Take(context.Background(), "other", policy, now)
+	assert.True(r.Allowed, "keys are counted separately")
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	assert := a.New(t)
+
+	s := NewMemoryStore()
+	policy := Policy{Name: "p", Limit: 2, Window: 10 * time.Second, Algorithm: SlidingWindow}
+	start := time.Now().Truncate(policy.Window)
+
+	r, _ := s.Take(context.Background(), "k", policy, start)
+	assert.Equal(Result{Allowed: true, Remaining: 1, Reset: 10 * time.Second}, r)
+	r, _ = s.Take(context.Background(), "k", policy, start.Add(time.Second))
+	assert.True(r.Allowed)
+	r, _ = s.Take(context.Background(), "k", policy, start.Add(2*time.Second))
+	assert.False(r.Allowed)
+	assert.Equal(8*time.Second, r.Reset)
+
+	// the previous window still counts with 50%
+	r, _ = s.Take(context.Background(), "k", policy, start.Add(15*time.Second))
+	assert.Equal(Result{Allowed: true, Remaining: 0, Reset: 5 * time.Second}, r)
+	r, _ = s.Take(context.Background(), "k", policy, start.Add(16*time.Second))
+	assert.False(r.Allowed)
+
+	// windows without requests reset the count
+	r, _ = s.Take(context.Background(), "k", policy, start.Add(40*time.Second))
+	assert.Equal(1, r.Remaining)
+}
+
+func TestMemoryStore_RemovesExpiredBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	policy := Policy{Name: "p", Limit: 2, Window: time.Second}
+	now := time.Now()
+
+	_, _ = s.Take(context.Background(), "k", policy, now)
+	_, _ = s.Take(context.Background(), "other", policy, now.Add(2*memoryStoreSweepInterval))
+
+	a.Len(t, s.buckets, 1)
+}
+
+func TestMemoryStore_EvictsWhenFull(t *testing.T) {
+	assert := a.New(t)
+
+	s := NewMemoryStoreWithMaxKeys(2)
+	policy := Policy{Name: "p", Limit: 2, Window: time.Minute}
+	now := time.Now()
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		result, err := s.Take(context.Background(), key, policy, now)
+		assert.NoError(err)
+		assert.True(result.Allowed)
+	}
+
+	assert.Len(s.buckets, 2)
+	assert.Contains(s.buckets, "d")
+}
+
+func TestMemoryStore_PrefersExpiredBucketsForEviction(t *testing.T) {
+	assert := a.New(t)
+
+	s := NewMemoryStoreWithMaxKeys(2)
+	now := time.Now()
+
+	_, _ = s.Take(context.Background(), "expired", Policy{Name: "short", Limit: 2, Window: time.Second}, now)
+	_, _ = s.Take(context.Background(), "active", Policy{Name: "long", Limit: 2, Window: time.Hour}, now)
+	_, _ = s.Take(context.Background(), "new", Policy{Name: "long", Limit: 2, Window: time.Hour}, now.Add(2*time.Second))
+
+	assert.Len(s.buckets, 2)
+	assert.Contains(s.buckets, "active")
+	assert.Contains(s.buckets, "new")
+}
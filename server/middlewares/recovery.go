@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/requestid"
+	"github.com/stfsy/go-api-kit/server/tracing"
+	"github.com/stfsy/go-api-kit/utils"
+	"github.com/urfave/negroni/v3"
+)
+
+var recoveryLogger = utils.NewLogger("recovery-middleware")
+
+// PanicReporter receives panics recovered by the RecoveryMiddleware, e.g. to send them
+// to an error tracking service.
+type PanicReporter func(r *http.Request, recovered any, stack []byte)
+
+// RecoveryMiddleware recovers from panics of later handlers. It logs the panic with its
+// stack trace and the request ID, records it on the span of the request and responds
+// with SendInternalServerError if the handler has not written a response yet. Panics
+// with http.ErrAbortHandler are passed on, so the server aborts the response silently.
+type RecoveryMiddleware struct {
+	// Reporter is called for each recovered panic if set.
+	Reporter PanicReporter
+}
+
+func NewRecoveryMiddleware() *RecoveryMiddleware {
+	return &RecoveryMiddleware{}
+}
+
+func (m *RecoveryMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+
+		stack := debug.Stack()
+		recoveryLogger.Error("Recovered from panic",
+			"panic", fmt.Sprint(recovered),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"request_id", requestid.FromContext(r.Context()),
+			"stack", string(stack),
+		)
+		tracing.RecordError(r.Context(), fmt.Errorf("panic: %v", recovered))
+
+		if m.Reporter != nil {
+			m.Reporter(r, recovered, stack)
+		}
+
+		if res, ok := rw.(negroni.ResponseWriter); ok && res.Written() {
+			// the status is already sent, all that can be done is to stop writing
+			return
+		}
+		handlers.SendInternalServerError(rw, nil)
+	}()
+
+	next(rw, r)
+}
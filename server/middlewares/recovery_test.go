@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveRecovery(m *RecoveryMiddleware, fn http.HandlerFunc) *httptest.ResponseRecorder {
+	n := negroni.New()
+	n.Use(NewRequestIdMiddleware())
+	n.Use(m)
+	n.UseHandlerFunc(fn)
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec
+}
+
+func TestRecoveryMiddleware_SendsProblemJson(t *testing.T) {
+	assert := a.New(t)
+
+	var reported any
+	var stack []byte
+	m := NewRecoveryMiddleware()
+	m.Reporter = func(r *http.Request, recovered any, s []byte) {
+		reported = recovered
+		stack = s
+	}
+
+	rec := serveRecovery(m, func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("boom"))
+	})
+
+	assert.Equal(http.StatusInternalServerError, rec.Code)
+	assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+
+	var payload handlers.HttpError
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&payload))
+	assert.Equal("Internal Server Error", payload.Title)
+	assert.NotEmpty(payload.RequestId)
+
+	assert.EqualError(reported.(error), "boom")
+	assert.Contains(string(stack), "recovery_test.go")
+}
+
+func TestRecoveryMiddleware_KeepsWrittenResponse(t *testing.T) {
+	assert := a.New(t)
+
+	rec := serveRecovery(NewRecoveryMiddleware(), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	})
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("partial", rec.Body.String())
+}
+
+func TestRecoveryMiddleware_PassesOnErrAbortHandler(t *testing.T) {
+	reported := false
+	m := NewRecoveryMiddleware()
+	m.Reporter = func(r *http.Request, recovered any, stack []byte) { reported = true }
+
+	a.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serveRecovery(m, func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+	})
+	a.False(t, reported)
+}
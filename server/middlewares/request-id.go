@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/requestid"
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+// RequestIdMiddleware assigns an ID to each request. It accepts the X-Request-Id header
+// of the client if it is safe and not longer than requestid.MaxLength, otherwise it
+// generates an ID. The ID is stored in the request context, echoed in the X-Request-Id
+// response header and included in problem+json error responses.
+type RequestIdMiddleware struct{}
+
+func NewRequestIdMiddleware() *RequestIdMiddleware {
+	return &RequestIdMiddleware{}
+}
+
+func (m *RequestIdMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id, ok := utils.GetSafeHeaderValue(requestid.HeaderName, r.Header)
+	if !ok || id == "" || len(id) > requestid.MaxLength {
+		id = requestid.New()
+	}
+
+	rw.Header().Set(requestid.HeaderName, id)
+	next.ServeHTTP(rw, r.WithContext(requestid.NewContext(r.Context(), id)))
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/requestid"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveRequestId(header string) (*httptest.ResponseRecorder, string) {
+	var contextId string
+	n := negroni.New()
+	n.Use(NewRequestIdMiddleware())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextId = requestid.FromContext(r.Context())
+		handlers.SendNotFound(w, nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(requestid.HeaderName, header)
+	}
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec, contextId
+}
+
+func TestRequestIdMiddleware_AcceptsSafeIncomingId(t *testing.T) {
+	assert := a.New(t)
+
+	rec, contextId := serveRequestId("abc-123")
+
+	assert.Equal("abc-123", contextId)
+	assert.Equal("abc-123", rec.Header().Get(requestid.HeaderName))
+
+	var payload handlers.HttpError
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&payload))
+	assert.Equal("abc-123", payload.RequestId)
+}
+
+func TestRequestIdMiddleware_GeneratesId(t *testing.T) {
+	for name, header := range map[string]string{
+		"missing":  "",
+		"unsafe":   "abc\x7f",
+		"too long": strings.Repeat("a", requestid.MaxLength+1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := a.New(t)
+
+			rec, contextId := serveRequestId(header)
+
+			assert.Len(contextId, 36)
+			assert.NotEqual(header, contextId)
+			assert.Equal(contextId, rec.Header().Get(requestid.HeaderName))
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/route"
 )
 
 type RequireMaxBodyLengthMiddleware struct {
@@ -20,7 +21,12 @@ func NewRequireMaxBodyLengthMiddleware() *RequireMaxBodyLengthMiddleware {
 }
 
 // ServeHTTP enforces a maximum request body length, responding with 413 Payload Too Large if exceeded.
+// The MaxBodySize option of the matched route overrides the configured limit.
 func (m *RequireMaxBodyLengthMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	r.Body = http.MaxBytesReader(rw, r.Body, int64(m.maxSize))
+	maxSize := int64(m.maxSize)
+	if routeMaxSize := route.OptionsFromContext(r.Context()).MaxBodySize; routeMaxSize > 0 {
+		maxSize = routeMaxSize
+	}
+	r.Body = http.MaxBytesReader(rw, r.Body, maxSize)
 	next.ServeHTTP(rw, r)
 }
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/stfsy/go-api-kit/server/route"
 	"github.com/stretchr/testify/assert"
 	"github.com/urfave/negroni/v3"
 )
@@ -51,3 +52,26 @@ func TestRequireMaxBodyLengthMiddleware(t *testing.T) {
 		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
 	})
 }
+
+func TestRequireMaxBodyLengthMiddleware_UsesRouteMaxBodySize(t *testing.T) {
+	mw := &RequireMaxBodyLengthMiddleware{maxSize: 10}
+
+	n := negroni.New()
+	n.Use(mw)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	})
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(bytes.Repeat([]byte("a"), 20)))
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+		Options: route.Options{MaxBodySize: 20},
+	}))
+	rec := httptest.NewRecorder()
+
+	n.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
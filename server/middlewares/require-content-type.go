@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
 )
 
 type RequireContentTypeMiddleware struct {
@@ -15,23 +16,27 @@ type RequireContentTypeMiddleware struct {
 }
 
 func NewRequireContentTypeMiddleware(allowedContentType string) *RequireContentTypeMiddleware {
-	// Normalize allowed content type: parse and keep the media type only.
-	allowed := strings.ToLower(strings.TrimSpace(allowedContentType))
-	if i := strings.Index(allowed, ";"); i > -1 {
-		allowed = allowed[0:i]
-	}
-	// In case caller passed parameters, attempt to parse, else keep the raw media type.
-	if mt, _, err := mime.ParseMediaType(allowed); err == nil {
-		allowed = strings.ToLower(strings.TrimSpace(mt))
+	return &RequireContentTypeMiddleware{
+		AllowedContentType: normalizeMediaType(allowedContentType),
 	}
+}
 
-	return &RequireContentTypeMiddleware{
-		AllowedContentType: allowed,
+// normalizeMediaType lowercases contentType and strips its parameters.
+func normalizeMediaType(contentType string) string {
+	normalized := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.Index(normalized, ";"); i > -1 {
+		normalized = normalized[0:i]
 	}
+	// In case caller passed parameters, attempt to parse, else keep the raw media type.
+	if mt, _, err := mime.ParseMediaType(normalized); err == nil {
+		normalized = strings.ToLower(strings.TrimSpace(mt))
+	}
+	return normalized
 }
 
 // AllowContentType enforces a whitelist of request Content-Types otherwise responds
-// with a 415 Unsupported Media Type status.
+// with a 415 Unsupported Media Type status. The ContentTypes option of the matched
+// route replaces the allowed content type.
 func (m *RequireContentTypeMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	// Only enforce content-type for write requests. Non-write requests are passed through.
 	if !isWriteRequest(r) {
@@ -39,6 +44,11 @@ func (m *RequireContentTypeMiddleware) ServeHTTP(rw http.ResponseWriter, r *http
 		return
 	}
 
+	if routeContentTypes := route.OptionsFromContext(r.Context()).ContentTypes; len(routeContentTypes) > 0 {
+		m.serveRouteContentTypes(rw, r, next, routeContentTypes)
+		return
+	}
+
 	// For write requests (POST/PUT/PATCH and DELETE with body), require a valid Content-Type.
 	// Parse the Content-Type header using mime.ParseMediaType to canonicalize comparisons.
 	ctHeader := strings.TrimSpace(r.Header.Get("Content-Type"))
@@ -69,6 +79,24 @@ func (m *RequireContentTypeMiddleware) ServeHTTP(rw http.ResponseWriter, r *http
 	handlers.SendUnsupportedMediaType(rw, nil)
 }
 
+func (m *RequireContentTypeMiddleware) serveRouteContentTypes(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc, allowed []string) {
+	mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(r.Header.Get("Content-Type")))
+	if err != nil {
+		handlers.SendUnsupportedMediaType(rw, nil)
+		return
+	}
+
+	mediaType = strings.ToLower(mediaType)
+	for _, contentType := range allowed {
+		if normalizeMediaType(contentType) == mediaType {
+			next.ServeHTTP(rw, r)
+			return
+		}
+	}
+
+	handlers.SendUnsupportedMediaType(rw, nil)
+}
+
 func isWriteRequest(r *http.Request) bool {
 	switch r.Method {
 	case http.MethodPatch, http.MethodPost, http.MethodPut:
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/stfsy/go-api-kit/server/route"
 	"github.com/urfave/negroni/v3"
 )
 
@@ -207,3 +208,31 @@ func TestContentType(t *testing.T) {
 		})
 	}
 }
+
+func TestContentType_UsesRouteContentTypes(t *testing.T) {
+	t.Parallel()
+
+	n := negroni.New()
+	n.Use(NewRequireContentTypeMiddleware("application/json"))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for contentType, want := range map[string]int{
+		"multipart/form-data; boundary=x": http.StatusOK,
+		"TEXT/CSV":                        http.StatusOK,
+		"application/json":                http.StatusUnsupportedMediaType,
+		"":                                http.StatusUnsupportedMediaType,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("x")))
+		req.Header.Set("Content-Type", contentType)
+		req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+			Options: route.Options{ContentTypes: []string{"multipart/form-data", "text/csv"}},
+		}))
+		rec := httptest.NewRecorder()
+
+		n.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("content type %q: got %d, want %d", contentType, rec.Code, want)
+		}
+	}
+}
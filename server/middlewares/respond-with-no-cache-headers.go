@@ -5,6 +5,8 @@ package middlewares
 
 import (
 	"net/http"
+
+	"github.com/stfsy/go-api-kit/server/cache"
 )
 
 var noCacheHeaders = map[string]string{
@@ -15,6 +17,9 @@ var noCacheHeaders = map[string]string{
 	"X-Accel-Expires":   "0",
 }
 
+// NoCacheHeadersMiddleware forbids caching of all responses.
+//
+// Deprecated: use CachePolicyMiddleware, which supports per-route cache policies.
 type NoCacheHeadersMiddleware struct{}
 
 func NewNoCacheHeadersMiddleware() *NoCacheHeadersMiddleware {
@@ -31,13 +36,7 @@ func NewNoCacheHeadersMiddleware() *NoCacheHeadersMiddleware {
 //	X-Accel-Expires: 0
 //	Pragma: no-cache (for HTTP/1.0 proxies/clients)
 func (m *NoCacheHeadersMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-
-	// Set our NoCache headers. Keys are precomputed in canonical form, so write
-	// directly to skip Set's canonicalization.
-	headers := rw.Header()
-	for k, v := range noCacheHeaders {
-		headers[k] = []string{v}
-	}
+	applyCachePolicy(rw.Header(), cache.NoStore())
 
 	next.ServeHTTP(rw, r)
 }
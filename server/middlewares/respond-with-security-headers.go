@@ -1,38 +1,161 @@
 package middlewares
 
 import (
+	"fmt"
 	"net/http"
+	"sync"
 
+	"github.com/stfsy/go-api-kit/config"
 	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/utils"
 )
 
-type SecurityHeadersMiddleware struct{}
-
-var securityHeadersMap = func() map[string]string {
-	m := make(map[string]string, 11)
-	m[security.NewCrossOriginEmbedderPolicy().Name] = security.NewCrossOriginEmbedderPolicy().Value
-	m[security.NewCrossOriginOpenerPolicy().Name] = security.NewCrossOriginOpenerPolicy().Value
-	m[security.NewCrossOriginResourcePolicy().Name] = security.NewCrossOriginResourcePolicy().Value
-	m[security.NewOriginAgentClusterPolicy().Name] = security.NewOriginAgentClusterPolicy().Value
-	m[security.NewReferrerPolicy().Name] = security.NewReferrerPolicy().Value
-	m[security.NewStrictTransportSecurityPolicy().Name] = security.NewStrictTransportSecurityPolicy().Value
-	m[security.NewXContentTypeOptions().Name] = security.NewXContentTypeOptions().Value
-	m[security.NewXDownloadOptions().Name] = security.NewXDownloadOptions().Value
-	m[security.NewXFrameOptions().Name] = security.NewXFrameOptions().Value
-	m[security.NewXPermittedCrossDomainOptions().Name] = security.NewXPermittedCrossDomainOptions().Value
-	m[security.NewXssProtection().Name] = security.NewXssProtection().Value
-	return m
-}()
+var securityHeadersLogger = utils.NewLogger("security-headers")
+
+// headerOff omits a header that is configured by an environment variable.
+const headerOff = "off"
+
+// SecurityHeadersMiddleware adds the headers of a security.Config to all responses.
+// Routes with route.SecurityHeaders get the headers of their own config instead.
+//
+// If a header contains security.SourceNonce, a new nonce is generated for each request,
+// inserted into the header and stored in the request context, see security.NonceFromContext.
+type SecurityHeadersMiddleware struct {
+	headers securityHeaders
+	// routeHeaders caches the headers of route configs by pointer
+	routeHeaders sync.Map
+}
+
+type securityHeaders struct {
+	values []security.HeaderKeyValue
+	nonce  bool
+}
+
+func newSecurityHeaders(c security.Config) securityHeaders {
+	h := securityHeaders{values: c.Headers()}
+	for _, v := range h.values {
+		h.nonce = h.nonce || security.UsesNonce(v.Value)
+	}
+	return h
+}
 
+// NewRespondWithSecurityHeadersMiddleware returns a middleware configured like
+// NewSecurityHeadersMiddleware. Invalid values are logged and the default preset is used.
+//
+// Deprecated: use NewSecurityHeadersMiddleware, which returns the error.
 func NewRespondWithSecurityHeadersMiddleware() *SecurityHeadersMiddleware {
-	return &SecurityHeadersMiddleware{}
+	m, err := NewSecurityHeadersMiddleware()
+	if err != nil {
+		securityHeadersLogger.Error(fmt.Sprintf("Invalid security headers configuration, using default preset: %s", err.Error()))
+		m = &SecurityHeadersMiddleware{headers: newSecurityHeaders(security.DefaultPreset())}
+	}
+	return m
+}
+
+// NewSecurityHeadersMiddleware returns a middleware with the preset selected by
+// API_KIT_SECURITY_HEADERS_PRESET and the header values of the API_KIT_* environment
+// variables. It returns an error if the preset is unknown or a value is invalid.
+func NewSecurityHeadersMiddleware() (*SecurityHeadersMiddleware, error) {
+	c, err := securityConfigFromEnv(config.Get())
+	if err != nil {
+		return nil, err
+	}
+	return &SecurityHeadersMiddleware{headers: newSecurityHeaders(c)}, nil
+}
+
+// NewSecurityHeadersMiddlewareWithConfig returns a middleware that adds the headers of c.
+// It returns an error if c is invalid.
+func NewSecurityHeadersMiddlewareWithConfig(c security.Config) (*SecurityHeadersMiddleware, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &SecurityHeadersMiddleware{headers: newSecurityHeaders(c)}, nil
+}
+
+func securityConfigFromEnv(c config.Configuration) (security.Config, error) {
+	sc, err := security.Preset(c.SecurityHeadersPreset)
+	if err != nil {
+		return sc, err
+	}
+
+	if c.StrictTransportSecurity == headerOff {
+		sc.StrictTransportSecurity = nil
+	} else if c.StrictTransportSecurity != "" {
+		hsts, err := security.ParseStrictTransportSecurity(c.StrictTransportSecurity)
+		if err != nil {
+			return sc, err
+		}
+		sc.StrictTransportSecurity = &hsts
+	}
+	if c.ContentSecurityPolicy == headerOff {
+		sc.ContentSecurityPolicy = nil
+	} else if c.ContentSecurityPolicy != "" {
+		csp, err := security.ParseContentSecurityPolicy(c.ContentSecurityPolicy)
+		if err != nil {
+			return sc, err
+		}
+		sc.ContentSecurityPolicy = &csp
+	}
+	if c.PermissionsPolicy == headerOff {
+		sc.PermissionsPolicy = nil
+	} else if c.PermissionsPolicy != "" {
+		pp, err := security.ParsePermissionsPolicy(c.PermissionsPolicy)
+		if err != nil {
+			return sc, err
+		}
+		sc.PermissionsPolicy = &pp
+	}
+
+	overrideHeader(&sc.FrameOptions, c.FrameOptions)
+	overrideHeader(&sc.ReferrerPolicy, c.ReferrerPolicy)
+	overrideHeader(&sc.CrossOriginEmbedderPolicy, c.CrossOriginEmbedderPolicy)
+	overrideHeader(&sc.CrossOriginOpenerPolicy, c.CrossOriginOpenerPolicy)
+	overrideHeader(&sc.CrossOriginResourcePolicy, c.CrossOriginResourcePolicy)
+
+	return sc, sc.Validate()
+}
+
+// overrideHeader replaces value with env unless env is empty. off omits the header.
+func overrideHeader[T ~string](value *T, env string) {
+	switch env {
+	case "":
+	case headerOff:
+		*value = ""
+	default:
+		*value = T(env)
+	}
 }
 
 func (m *SecurityHeadersMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	securityHeaders := m.headers
+	if rc := route.OptionsFromContext(r.Context()).SecurityHeaders; rc != nil {
+		securityHeaders = m.routeHeadersOf(rc)
+	}
+
 	headers := rw.Header()
-	for k, v := range securityHeadersMap {
-		// Use the canonical header key directly to avoid the extra validation path in Header.Set.
-		headers[http.CanonicalHeaderKey(k)] = []string{v}
+	if securityHeaders.nonce {
+		nonce := security.NewNonce()
+		for _, h := range securityHeaders.values {
+			headers[h.Name] = []string{security.WithNonce(h.Value, nonce)}
+		}
+		next(rw, r.WithContext(security.NewNonceContext(r.Context(), nonce)))
+		return
+	}
+
+	for _, h := range securityHeaders.values {
+		// Names are in canonical form, so write directly to skip the validation of Header.Set.
+		headers[h.Name] = []string{h.Value}
 	}
 	next(rw, r)
 }
+
+func (m *SecurityHeadersMiddleware) routeHeadersOf(c *security.Config) securityHeaders {
+	if headers, ok := m.routeHeaders.Load(c); ok {
+		return headers.(securityHeaders)
+	}
+	headers := newSecurityHeaders(*c)
+	m.routeHeaders.Store(c, headers)
+	return headers
+}
@@ -3,9 +3,14 @@ package middlewares
 import (
 	"bytes"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
 	"github.com/urfave/negroni/v3"
 )
 
@@ -83,3 +88,120 @@ func TestSecurityHeaders(t *testing.T) {
 		})
 	}
 }
+
+func serveSecurityHeaders(m *SecurityHeadersMiddleware, opts ...route.Option) *httptest.ResponseRecorder {
+	n := negroni.New()
+	n.Use(m)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if len(opts) > 0 {
+		req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+			Pattern: "GET /",
+			Options: route.Options{}.Apply(opts...),
+		}))
+	}
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSecurityHeaders_WithConfig(t *testing.T) {
+	assert := a.New(t)
+
+	_, err := NewSecurityHeadersMiddlewareWithConfig(security.Config{ReferrerPolicy: "never"})
+	assert.EqualError(err, "value never of header Referrer-Policy is invalid")
+
+	m, err := NewSecurityHeadersMiddlewareWithConfig(security.JSONAPIPreset())
+	assert.NoError(err)
+	rec := serveSecurityHeaders(m)
+	assert.Equal("default-src 'none'; frame-ancestors 'none'", rec.Header().Get("Content-Security-Policy"))
+	assert.Equal("0", rec.Header().Get("X-XSS-Protection"))
+}
+
+func TestSecurityHeaders_RouteOverride(t *testing.T) {
+	assert := a.New(t)
+
+	c := security.BrowserAppPreset()
+	csp := c.ContentSecurityPolicy.ScriptSrc(security.SourceSelf, "https://cdn.example.com")
+	c.ContentSecurityPolicy = &csp
+	opt := route.SecurityHeaders(c)
+
+	m := NewRespondWithSecurityHeadersMiddleware()
+	for range 2 {
+		rec := serveSecurityHeaders(m, opt)
+		assert.Contains(rec.Header().Get("Content-Security-Policy"), "script-src 'self' https://cdn.example.com")
+		assert.Equal("SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+		assert.Empty(rec.Header().Get("Cross-Origin-Embedder-Policy"))
+	}
+
+	rec := serveSecurityHeaders(m)
+	assert.Equal("DENY", rec.Header().Get("X-Frame-Options"))
+}
+
+func TestSecurityHeaders_ConfigFromEnv(t *testing.T) {
+	assert := a.New(t)
+
+	c := config.Configuration{}
+	c.SecurityHeadersPreset = security.PresetNameJSONAPI
+	c.StrictTransportSecurity = "max-age=600"
+	c.ContentSecurityPolicy = headerOff
+	c.PermissionsPolicy = "camera=()"
+	c.FrameOptions = "SAMEORIGIN"
+	c.CrossOriginEmbedderPolicy = headerOff
+
+	sc, err := securityConfigFromEnv(c)
+	assert.NoError(err)
+	assert.Equal("max-age=600", sc.StrictTransportSecurity.String())
+	assert.Nil(sc.ContentSecurityPolicy)
+	assert.Equal("camera=()", sc.PermissionsPolicy.String())
+	assert.Equal(security.FrameSameOrigin, sc.FrameOptions)
+	assert.Empty(sc.CrossOriginEmbedderPolicy)
+	assert.Equal(security.ReferrerNoReferrer, sc.ReferrerPolicy)
+
+	c.FrameOptions = "ALLOW"
+	_, err = securityConfigFromEnv(c)
+	assert.EqualError(err, "value ALLOW of header X-Frame-Options is invalid")
+
+	c.SecurityHeadersPreset = "unknown"
+	_, err = securityConfigFromEnv(c)
+	assert.EqualError(err, "security headers preset unknown is unknown")
+}
+
+func TestSecurityHeaders_GeneratesNoncePerRequest(t *testing.T) {
+	assert := a.New(t)
+
+	c := security.BrowserAppPreset()
+	csp := c.ContentSecurityPolicy.ScriptSrc(security.SourceNonce, security.SourceStrictDynamic)
+	c.ContentSecurityPolicy = &csp
+	m, err := NewSecurityHeadersMiddlewareWithConfig(c)
+	assert.NoError(err)
+
+	var nonces []string
+	for range 2 {
+		var nonce string
+		n := negroni.New()
+		n.Use(m)
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = security.NonceFromContext(r.Context())
+		})
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		assert.Len(nonce, 24)
+		assert.Contains(rec.Header().Get("Content-Security-Policy"), "script-src 'nonce-"+nonce+"' 'strict-dynamic'")
+		nonces = append(nonces, nonce)
+	}
+	assert.NotEqual(nonces[0], nonces[1])
+}
+
+func TestSecurityHeaders_NoNonceWithoutPlaceholder(t *testing.T) {
+	var nonce string
+	n := negroni.New()
+	n.Use(NewRespondWithSecurityHeadersMiddleware())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = security.NonceFromContext(r.Context())
+	})
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	a.Empty(t, nonce)
+}
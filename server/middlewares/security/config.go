@@ -0,0 +1,201 @@
+package security
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Config selects the security headers of responses. Empty, false and nil fields omit
+// the header.
+type Config struct {
+	StrictTransportSecurity *StrictTransportSecurity
+	ContentSecurityPolicy   *ContentSecurityPolicy
+	PermissionsPolicy       *PermissionsPolicy
+	// ReportingEndpoints maps endpoint names to URLs that receive reports of the browser,
+	// e.g. {"csp": "https://example.com/reports"} for ContentSecurityPolicy.ReportTo("csp").
+	ReportingEndpoints           map[string]string
+	CrossOriginEmbedderPolicy    CrossOriginEmbedderPolicy
+	CrossOriginOpenerPolicy      CrossOriginOpenerPolicy
+	CrossOriginResourcePolicy    CrossOriginResourcePolicy
+	ReferrerPolicy               ReferrerPolicy
+	FrameOptions                 FrameOptions
+	PermittedCrossDomainPolicies PermittedCrossDomainPolicies
+	XSSProtection                XSSProtection
+	// OriginAgentCluster sends Origin-Agent-Cluster: ?1.
+	OriginAgentCluster bool
+	// NoSniff sends X-Content-Type-Options: nosniff.
+	NoSniff bool
+	// NoOpen sends X-Download-Options: noopen.
+	NoOpen bool
+}
+
+// Names of the presets accepted by Preset.
+const (
+	PresetNameDefault    = "default"
+	PresetNameJSONAPI    = "api"
+	PresetNameBrowserApp = "browser"
+)
+
+// DefaultPreset returns the headers sent by earlier versions of this module.
+func DefaultPreset() Config {
+	hsts := StrictTransportSecurity{MaxAge: preloadMinMaxAge, IncludeSubDomains: true, Preload: true}
+	return Config{
+		StrictTransportSecurity:      &hsts,
+		CrossOriginEmbedderPolicy:    EmbedderRequireCorp,
+		CrossOriginOpenerPolicy:      OpenerSameOrigin,
+		CrossOriginResourcePolicy:    ResourceSameSite,
+		ReferrerPolicy:               ReferrerSameOrigin,
+		FrameOptions:                 FrameDeny,
+		PermittedCrossDomainPolicies: CrossDomainNone,
+		XSSProtection:                XSSProtectionBlock,
+		OriginAgentCluster:           true,
+		NoSniff:                      true,
+		NoOpen:                       true,
+	}
+}
+
+// JSONAPIPreset returns strict headers for APIs that only serve JSON. Browsers must not
+// render, frame or load anything from the responses.
+func JSONAPIPreset() Config {
+	c := DefaultPreset()
+	csp := NewContentSecurityPolicy().DefaultSrc(SourceNone).FrameAncestors(SourceNone)
+	c.ContentSecurityPolicy = &csp
+	c.CrossOriginResourcePolicy = ResourceSameOrigin
+	c.ReferrerPolicy = ReferrerNoReferrer
+	c.XSSProtection = XSSProtectionDisabled
+	return c
+}
+
+// BrowserAppPreset returns headers for applications that serve HTML to browsers. Resources
+// are loaded from the own origin and powerful browser features are denied. Cross-Origin-
+// Embedder-Policy is omitted, because it blocks images and scripts of other origins
+// without CORS or CORP headers.
+func BrowserAppPreset() Config {
+	c := DefaultPreset()
+	csp := NewContentSecurityPolicy().
+		DefaultSrc(SourceSelf).
+		ObjectSrc(SourceNone).
+		BaseURI(SourceSelf).
+		FormAction(SourceSelf).
+		FrameAncestors(SourceSelf).
+		UpgradeInsecureRequests()
+	pp := NewPermissionsPolicy().Deny("camera", "microphone", "geolocation", "payment", "usb", "interest-cohort")
+	c.ContentSecurityPolicy = &csp
+	c.PermissionsPolicy = &pp
+	c.CrossOriginEmbedderPolicy = ""
+	c.ReferrerPolicy = ReferrerStrictOriginWhenCrossOrigin
+	c.FrameOptions = FrameSameOrigin
+	c.XSSProtection = XSSProtectionDisabled
+	return c
+}
+
+// Preset returns the preset with the given name.
+func Preset(name string) (Config, error) {
+	switch name {
+	case "", PresetNameDefault:
+		return DefaultPreset(), nil
+	case PresetNameJSONAPI:
+		return JSONAPIPreset(), nil
+	case PresetNameBrowserApp:
+		return BrowserAppPreset(), nil
+	default:
+		return Config{}, fmt.Errorf("security headers preset %s is unknown", name)
+	}
+}
+
+// Validate returns an error if a header has an unknown or malformed value.
+func (c Config) Validate() error {
+	if c.StrictTransportSecurity != nil {
+		err := c.StrictTransportSecurity.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if c.ContentSecurityPolicy != nil {
+		err := c.ContentSecurityPolicy.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if c.PermissionsPolicy != nil {
+		err := c.PermissionsPolicy.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, url := range c.ReportingEndpoints {
+		if !isDirectiveName(name) || url == "" || strings.ContainsAny(url, "\" ,;\t") {
+			return fmt.Errorf("reporting endpoint %s with url %q is invalid", name, url)
+		}
+	}
+
+	for _, v := range []struct {
+		header string
+		value  string
+		valid  []string
+	}{
+		{"Cross-Origin-Embedder-Policy", string(c.CrossOriginEmbedderPolicy), []string{string(EmbedderRequireCorp), string(EmbedderCredentialless), string(EmbedderUnsafeNone)}},
+		{"Cross-Origin-Opener-Policy", string(c.CrossOriginOpenerPolicy), []string{string(OpenerSameOrigin), string(OpenerSameOriginAllowPopups), string(OpenerUnsafeNone)}},
+		{"Cross-Origin-Resource-Policy", string(c.CrossOriginResourcePolicy), []string{string(ResourceSameOrigin), string(ResourceSameSite), string(ResourceCrossOrigin)}},
+		{"Referrer-Policy", string(c.ReferrerPolicy), []string{
+			string(ReferrerNoReferrer), string(ReferrerNoReferrerWhenDowngrade), string(ReferrerOrigin), string(ReferrerOriginWhenCrossOrigin),
+			string(ReferrerSameOrigin), string(ReferrerStrictOrigin), string(ReferrerStrictOriginWhenCrossOrigin), string(ReferrerUnsafeURL),
+		}},
+		{"X-Frame-Options", string(c.FrameOptions), []string{string(FrameDeny), string(FrameSameOrigin)}},
+		{"X-Permitted-Cross-Domain-Policies", string(c.PermittedCrossDomainPolicies), []string{
+			string(CrossDomainNone), string(CrossDomainMasterOnly), string(CrossDomainByContentType), string(CrossDomainAll),
+		}},
+		{"X-XSS-Protection", string(c.XSSProtection), []string{string(XSSProtectionDisabled), string(XSSProtectionBlock)}},
+	} {
+		if v.value != "" && !slices.Contains(v.valid, v.value) {
+			return fmt.Errorf("value %s of header %s is invalid", v.value, v.header)
+		}
+	}
+	return nil
+}
+
+// Headers returns the headers selected by c in canonical form.
+func (c Config) Headers() []HeaderKeyValue {
+	var headers []HeaderKeyValue
+	add := func(name, value string) {
+		if value != "" {
+			headers = append(headers, HeaderKeyValue{Name: name, Value: value})
+		}
+	}
+	flag := func(name string, enabled bool, value string) {
+		if enabled {
+			add(name, value)
+		}
+	}
+
+	if c.StrictTransportSecurity != nil {
+		add("Strict-Transport-Security", c.StrictTransportSecurity.String())
+	}
+	if c.ContentSecurityPolicy != nil && !c.ContentSecurityPolicy.IsZero() {
+		add(c.ContentSecurityPolicy.HeaderName(), c.ContentSecurityPolicy.String())
+	}
+	if c.PermissionsPolicy != nil && !c.PermissionsPolicy.IsZero() {
+		add("Permissions-Policy", c.PermissionsPolicy.String())
+	}
+	if len(c.ReportingEndpoints) > 0 {
+		endpoints := make([]string, 0, len(c.ReportingEndpoints))
+		for _, name := range slices.Sorted(maps.Keys(c.ReportingEndpoints)) {
+			endpoints = append(endpoints, name+`="`+c.ReportingEndpoints[name]+`"`)
+		}
+		add("Reporting-Endpoints", strings.Join(endpoints, ", "))
+	}
+	add("Cross-Origin-Embedder-Policy", string(c.CrossOriginEmbedderPolicy))
+	add("Cross-Origin-Opener-Policy", string(c.CrossOriginOpenerPolicy))
+	add("Cross-Origin-Resource-Policy", string(c.CrossOriginResourcePolicy))
+	add("Referrer-Policy", string(c.ReferrerPolicy))
+	add("X-Frame-Options", string(c.FrameOptions))
+	add("X-Permitted-Cross-Domain-Policies", string(c.PermittedCrossDomainPolicies))
+	add("X-Xss-Protection", string(c.XSSProtection))
+	flag("Origin-Agent-Cluster", c.OriginAgentCluster, "?1")
+	flag("X-Content-Type-Options", c.NoSniff, "nosniff")
+	flag("X-Download-Options", c.NoOpen, "noopen")
+	return headers
+}
@@ -0,0 +1,171 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContentSecurityPolicy(t *testing.T) {
+	csp := NewContentSecurityPolicy().
+		DefaultSrc(SourceSelf).
+		ImgSrc(SourceSelf, SourceData).
+		ImgSrc("https://images.example.com").
+		FrameAncestors(SourceNone).
+		UpgradeInsecureRequests()
+
+	want := "default-src 'self'; img-src 'self' data: https://images.example.com; frame-ancestors 'none'; upgrade-insecure-requests"
+	if csp.String() != want {
+		t.Fatalf("String() = %q; want %q", csp.String(), want)
+	}
+	if csp.HeaderName() != "Content-Security-Policy" || csp.ReportOnly().HeaderName() != "Content-Security-Policy-Report-Only" {
+		t.Fatalf("unexpected header names")
+	}
+
+	parsed, err := ParseContentSecurityPolicy(want)
+	if err != nil || parsed.String() != want {
+		t.Fatalf("ParseContentSecurityPolicy() = %q, %v; want %q", parsed.String(), err, want)
+	}
+}
+
+func TestContentSecurityPolicy_BuilderDoesNotModifyReceiver(t *testing.T) {
+	base := NewContentSecurityPolicy().ScriptSrc(SourceSelf)
+	_ = base.ScriptSrc("https://cdn.example.com")
+	_ = base.StyleSrc(SourceSelf)
+
+	if base.String() != "script-src 'self'" {
+		t.Fatalf("base = %q", base.String())
+	}
+}
+
+func TestContentSecurityPolicy_Validate(t *testing.T) {
+	for _, csp := range []ContentSecurityPolicy{
+		NewContentSecurityPolicy().DefaultSrc(SourceNone, SourceSelf),
+		NewContentSecurityPolicy().Directive("script src", SourceSelf),
+		NewContentSecurityPolicy().ScriptSrc("'self';"),
+	} {
+		if csp.Validate() == nil {
+			t.Fatalf("expected %q to be invalid", csp.String())
+		}
+	}
+}
+
+func TestPermissionsPolicy(t *testing.T) {
+	pp := NewPermissionsPolicy().
+		Deny("camera", "microphone").
+		Allow("fullscreen", AllowSelf, "https://player.example.com").
+		Allow("autoplay", AllowAll)
+
+	want := `camera=(), microphone=(), fullscreen=(self "https://player.example.com"), autoplay=*`
+	if pp.String() != want {
+		t.Fatalf("String() = %q; want %q", pp.String(), want)
+	}
+
+	parsed, err := ParsePermissionsPolicy(want)
+	if err != nil || parsed.String() != want {
+		t.Fatalf("ParsePermissionsPolicy() = %q, %v; want %q", parsed.String(), err, want)
+	}
+
+	if NewPermissionsPolicy().Allow("camera", AllowAll, AllowSelf).Validate() == nil {
+		t.Fatalf("expected * combined with self to be invalid")
+	}
+	if NewPermissionsPolicy().Allow("camera", "example.com").Validate() == nil {
+		t.Fatalf("expected origin without scheme to be invalid")
+	}
+	if _, err := ParsePermissionsPolicy("camera=(self"); err == nil {
+		t.Fatalf("expected unterminated allowlist to be invalid")
+	}
+}
+
+func TestStrictTransportSecurity(t *testing.T) {
+	hsts, err := ParseStrictTransportSecurity("max-age=63072000; includeSubDomains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hsts.MaxAge != 730*24*time.Hour || !hsts.IncludeSubDomains || hsts.Preload {
+		t.Fatalf("unexpected %+v", hsts)
+	}
+	if hsts.String() != "max-age=63072000; includeSubDomains" {
+		t.Fatalf("String() = %q", hsts.String())
+	}
+
+	for _, value := range []string{"includeSubDomains", "max-age=-1", "max-age=60; preload; includeSubDomains", "max-age=60; foo"} {
+		if _, err := ParseStrictTransportSecurity(value); err == nil {
+			t.Fatalf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for _, name := range []string{PresetNameDefault, PresetNameJSONAPI, PresetNameBrowserApp} {
+		c, err := Preset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("preset %s is invalid: %s", name, err)
+		}
+	}
+	if _, err := Preset("unknown"); err == nil {
+		t.Fatalf("expected unknown preset to fail")
+	}
+
+	headers := map[string]string{}
+	for _, h := range JSONAPIPreset().Headers() {
+		headers[h.Name] = h.Value
+	}
+	if headers["Content-Security-Policy"] != "default-src 'none'; frame-ancestors 'none'" {
+		t.Fatalf("unexpected csp %q", headers["Content-Security-Policy"])
+	}
+	if headers["Referrer-Policy"] != "no-referrer" {
+		t.Fatalf("unexpected referrer policy %q", headers["Referrer-Policy"])
+	}
+}
+
+func TestConfig_OmitsEmptyHeadersAndRejectsUnknownValues(t *testing.T) {
+	c := Config{FrameOptions: FrameSameOrigin, NoSniff: true}
+	headers := c.Headers()
+	if len(headers) != 2 || headers[0].Name != "X-Frame-Options" || headers[1].Name != "X-Content-Type-Options" {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+
+	c.FrameOptions = "ALLOW-FROM https://example.com"
+	if c.Validate() == nil {
+		t.Fatalf("expected unknown frame option to be invalid")
+	}
+}
+
+func TestConfig_ReportingEndpoints(t *testing.T) {
+	c := Config{ReportingEndpoints: map[string]string{
+		"default": "https://example.com/reports",
+		"csp":     "https://example.com/reports/csp",
+	}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	headers := c.Headers()
+	want := `csp="https://example.com/reports/csp", default="https://example.com/reports"`
+	if len(headers) != 1 || headers[0].Name != "Reporting-Endpoints" || headers[0].Value != want {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+
+	c.ReportingEndpoints["bad"] = `https://example.com/"`
+	if c.Validate() == nil {
+		t.Fatalf("expected url with quote to be invalid")
+	}
+}
+
+func TestNonce(t *testing.T) {
+	csp := NewContentSecurityPolicy().ScriptSrc(SourceNonce)
+	if err := csp.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !UsesNonce(csp.String()) || UsesNonce("script-src 'self'") {
+		t.Fatalf("unexpected UsesNonce result")
+	}
+	if got := WithNonce(csp.String(), "abc"); got != "script-src 'nonce-abc'" {
+		t.Fatalf("WithNonce() = %q", got)
+	}
+	if NewNonce() == NewNonce() {
+		t.Fatalf("expected different nonces")
+	}
+}
@@ -0,0 +1,198 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source expressions with a special meaning in Content-Security-Policy directives.
+const (
+	SourceNone          = "'none'"
+	SourceSelf          = "'self'"
+	SourceUnsafeInline  = "'unsafe-inline'"
+	SourceUnsafeEval    = "'unsafe-eval'"
+	SourceStrictDynamic = "'strict-dynamic'"
+	SourceData          = "data:"
+	SourceHttps         = "https:"
+)
+
+type cspDirective struct {
+	name    string
+	sources []string
+}
+
+// ContentSecurityPolicy builds a Content-Security-Policy header, which restricts the
+// resources a browser may load for a document. Policies are immutable, the builder
+// methods return copies.
+//
+//	csp := security.NewContentSecurityPolicy().
+//		DefaultSrc(security.SourceSelf).
+//		ImgSrc(security.SourceSelf, "https://images.example.com").
+//		FrameAncestors(security.SourceNone)
+type ContentSecurityPolicy struct {
+	directives []cspDirective
+	reportOnly bool
+}
+
+// NewContentSecurityPolicy returns an empty policy.
+func NewContentSecurityPolicy() ContentSecurityPolicy {
+	return ContentSecurityPolicy{}
+}
+
+// Directive adds sources to the named directive, e.g. "worker-src". Directives without
+// sources, like upgrade-insecure-requests, are added without a value.
+func (p ContentSecurityPolicy) Directive(name string, sources ...string) ContentSecurityPolicy {
+	name = strings.ToLower(name)
+	directives := make([]cspDirective, len(p.directives), len(p.directives)+1)
+	copy(directives, p.directives)
+	p.directives = directives
+
+	for i, d := range p.directives {
+		if d.name == name {
+			p.directives[i].sources = append(append([]string(nil), d.sources...), sources...)
+			return p
+		}
+	}
+	p.directives = append(p.directives, cspDirective{name: name, sources: append([]string(nil), sources...)})
+	return p
+}
+
+func (p ContentSecurityPolicy) DefaultSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("default-src", sources...)
+}
+
+func (p ContentSecurityPolicy) ScriptSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("script-src", sources...)
+}
+
+func (p ContentSecurityPolicy) StyleSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("style-src", sources...)
+}
+
+func (p ContentSecurityPolicy) ImgSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("img-src", sources...)
+}
+
+func (p ContentSecurityPolicy) FontSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("font-src", sources...)
+}
+
+func (p ContentSecurityPolicy) ConnectSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("connect-src", sources...)
+}
+
+func (p ContentSecurityPolicy) ObjectSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("object-src", sources...)
+}
+
+func (p ContentSecurityPolicy) FrameSrc(sources ...string) ContentSecurityPolicy {
+	return p.Directive("frame-src", sources...)
+}
+
+// FrameAncestors restricts the documents that may embed the response, superseding X-Frame-Options.
+func (p ContentSecurityPolicy) FrameAncestors(sources ...string) ContentSecurityPolicy {
+	return p.Directive("frame-ancestors", sources...)
+}
+
+func (p ContentSecurityPolicy) BaseURI(sources ...string) ContentSecurityPolicy {
+	return p.Directive("base-uri", sources...)
+}
+
+func (p ContentSecurityPolicy) FormAction(sources ...string) ContentSecurityPolicy {
+	return p.Directive("form-action", sources...)
+}
+
+// UpgradeInsecureRequests makes browsers load http URLs of the document via https.
+func (p ContentSecurityPolicy) UpgradeInsecureRequests() ContentSecurityPolicy {
+	return p.Directive("upgrade-insecure-requests")
+}
+
+// ReportTo sends violation reports to the named endpoint group of the Reporting-Endpoints header.
+func (p ContentSecurityPolicy) ReportTo(group string) ContentSecurityPolicy {
+	return p.Directive("report-to", group)
+}
+
+// ReportURI sends violation reports to uri. Browsers that support report-to ignore it.
+func (p ContentSecurityPolicy) ReportURI(uri string) ContentSecurityPolicy {
+	return p.Directive("report-uri", uri)
+}
+
+// ReportOnly sends the policy as Content-Security-Policy-Report-Only, so violations are
+// reported but not blocked.
+func (p ContentSecurityPolicy) ReportOnly() ContentSecurityPolicy {
+	p.reportOnly = true
+	return p
+}
+
+// HeaderName returns Content-Security-Policy or Content-Security-Policy-Report-Only.
+func (p ContentSecurityPolicy) HeaderName() string {
+	if p.reportOnly {
+		return "Content-Security-Policy-Report-Only"
+	}
+	return "Content-Security-Policy"
+}
+
+// IsZero reports whether the policy has no directives.
+func (p ContentSecurityPolicy) IsZero() bool {
+	return len(p.directives) == 0
+}
+
+// Validate returns an error if a directive or source is malformed or if 'none' is
+// combined with other sources.
+func (p ContentSecurityPolicy) Validate() error {
+	for _, d := range p.directives {
+		if !isDirectiveName(d.name) {
+			return fmt.Errorf("content security policy directive %s is invalid", d.name)
+		}
+		for _, source := range d.sources {
+			if source == "" || strings.ContainsAny(source, ";, \t\r\n") {
+				return fmt.Errorf("source %q of content security policy directive %s is invalid", source, d.name)
+			}
+			if strings.EqualFold(source, SourceNone) && len(d.sources) > 1 {
+				return fmt.Errorf("source 'none' of content security policy directive %s must not be combined with other sources", d.name)
+			}
+		}
+	}
+	return nil
+}
+
+// String returns the header value.
+func (p ContentSecurityPolicy) String() string {
+	var b strings.Builder
+	for i, d := range p.directives {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(d.name)
+		for _, source := range d.sources {
+			b.WriteByte(' ')
+			b.WriteString(source)
+		}
+	}
+	return b.String()
+}
+
+// ParseContentSecurityPolicy parses a Content-Security-Policy header value.
+func ParseContentSecurityPolicy(value string) (ContentSecurityPolicy, error) {
+	p := NewContentSecurityPolicy()
+	for _, directive := range strings.Split(value, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		p = p.Directive(fields[0], fields[1:]...)
+	}
+	return p, p.Validate()
+}
+
+func isDirectiveName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
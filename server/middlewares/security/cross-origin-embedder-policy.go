@@ -5,8 +5,15 @@ type HeaderKeyValue struct {
 	Value string
 }
 
-type CrossOriginEmbedderPolicy struct{}
+// CrossOriginEmbedderPolicy is a value of the Cross-Origin-Embedder-Policy header.
+type CrossOriginEmbedderPolicy string
+
+const (
+	EmbedderRequireCorp    CrossOriginEmbedderPolicy = "require-corp"
+	EmbedderCredentialless CrossOriginEmbedderPolicy = "credentialless"
+	EmbedderUnsafeNone     CrossOriginEmbedderPolicy = "unsafe-none"
+)
 
 func NewCrossOriginEmbedderPolicy() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("Cross-Origin-Embedder-Policy", "require-corp")
+	return NewKeyValuePairProvider("Cross-Origin-Embedder-Policy", string(EmbedderRequireCorp))
 }
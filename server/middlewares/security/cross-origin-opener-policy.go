@@ -1,5 +1,14 @@
 package security
 
+// CrossOriginOpenerPolicy is a value of the Cross-Origin-Opener-Policy header.
+type CrossOriginOpenerPolicy string
+
+const (
+	OpenerSameOrigin            CrossOriginOpenerPolicy = "same-origin"
+	OpenerSameOriginAllowPopups CrossOriginOpenerPolicy = "same-origin-allow-popups"
+	OpenerUnsafeNone            CrossOriginOpenerPolicy = "unsafe-none"
+)
+
 func NewCrossOriginOpenerPolicy() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("Cross-Origin-Opener-Policy", "same-origin")
+	return NewKeyValuePairProvider("Cross-Origin-Opener-Policy", string(OpenerSameOrigin))
 }
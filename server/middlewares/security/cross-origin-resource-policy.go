@@ -1,5 +1,14 @@
 package security
 
+// CrossOriginResourcePolicy is a value of the Cross-Origin-Resource-Policy header.
+type CrossOriginResourcePolicy string
+
+const (
+	ResourceSameOrigin  CrossOriginResourcePolicy = "same-origin"
+	ResourceSameSite    CrossOriginResourcePolicy = "same-site"
+	ResourceCrossOrigin CrossOriginResourcePolicy = "cross-origin"
+)
+
 func NewCrossOriginResourcePolicy() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("Cross-Origin-Resource-Policy", "same-site")
+	return NewKeyValuePairProvider("Cross-Origin-Resource-Policy", string(ResourceSameSite))
 }
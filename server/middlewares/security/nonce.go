@@ -0,0 +1,50 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// SourceNonce is replaced with a random nonce per response, e.g. in
+// ScriptSrc(SourceNonce, SourceStrictDynamic). Templates add the nonce of the request,
+// see NonceFromContext, to their inline scripts and styles.
+const SourceNonce = "'nonce-{nonce}'"
+
+// noncePlaceholder is the part of SourceNonce that is replaced with the nonce.
+const noncePlaceholder = "{nonce}"
+
+type nonceContextKey struct{}
+
+// NewNonce returns a base64 encoded nonce of 128 random bits.
+func NewNonce() string {
+	var b [16]byte
+	// rand.Read never returns an error
+	_, _ = rand.Read(b[:])
+	return base64.StdEncoding.EncodeToString(b[:])
+}
+
+// NewNonceContext returns a copy of ctx that carries nonce.
+func NewNonceContext(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceContextKey{}, nonce)
+}
+
+// NonceFromContext returns the Content-Security-Policy nonce of the request or an empty
+// string if the policy has no SourceNonce.
+//
+//	<script nonce="{{ .Nonce }}">...</script>
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceContextKey{}).(string)
+	return nonce
+}
+
+// UsesNonce reports whether value contains the SourceNonce placeholder.
+func UsesNonce(value string) bool {
+	return strings.Contains(value, noncePlaceholder)
+}
+
+// WithNonce replaces the SourceNonce placeholder in value with nonce.
+func WithNonce(value string, nonce string) string {
+	return strings.ReplaceAll(value, noncePlaceholder, nonce)
+}
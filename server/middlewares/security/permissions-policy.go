@@ -0,0 +1,147 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Allowlist entries of Permissions-Policy features besides quoted origins.
+const (
+	AllowSelf = "self"
+	AllowAll  = "*"
+)
+
+type permissionsFeature struct {
+	name      string
+	allowlist []string
+}
+
+// PermissionsPolicy builds a Permissions-Policy header, which controls the browser
+// features a document and its frames may use. Policies are immutable, the builder
+// methods return copies.
+//
+//	pp := security.NewPermissionsPolicy().
+//		Deny("camera", "microphone").
+//		Allow("fullscreen", security.AllowSelf, "https://player.example.com")
+type PermissionsPolicy struct {
+	features []permissionsFeature
+}
+
+// NewPermissionsPolicy returns an empty policy.
+func NewPermissionsPolicy() PermissionsPolicy {
+	return PermissionsPolicy{}
+}
+
+// Deny disables features for the document and all frames.
+func (p PermissionsPolicy) Deny(features ...string) PermissionsPolicy {
+	for _, feature := range features {
+		p = p.Allow(feature)
+	}
+	return p
+}
+
+// Allow enables feature for the given origins, AllowSelf or AllowAll. Without origins the
+// feature is disabled.
+func (p PermissionsPolicy) Allow(feature string, origins ...string) PermissionsPolicy {
+	feature = strings.ToLower(feature)
+	features := make([]permissionsFeature, 0, len(p.features)+1)
+	for _, f := range p.features {
+		if f.name != feature {
+			features = append(features, f)
+		}
+	}
+	p.features = append(features, permissionsFeature{name: feature, allowlist: append([]string(nil), origins...)})
+	return p
+}
+
+// IsZero reports whether the policy has no features.
+func (p PermissionsPolicy) IsZero() bool {
+	return len(p.features) == 0
+}
+
+// Validate returns an error if a feature or origin is malformed or if AllowAll is
+// combined with other origins.
+func (p PermissionsPolicy) Validate() error {
+	for _, f := range p.features {
+		if !isDirectiveName(f.name) {
+			return fmt.Errorf("permissions policy feature %s is invalid", f.name)
+		}
+		for _, origin := range f.allowlist {
+			switch {
+			case origin == AllowAll:
+				if len(f.allowlist) > 1 {
+					return fmt.Errorf("allowlist * of permissions policy feature %s must not be combined with other origins", f.name)
+				}
+			case origin == AllowSelf || origin == "src":
+			case strings.HasPrefix(origin, "https://") || strings.HasPrefix(origin, "http://"):
+				if strings.ContainsAny(origin, "\" ,;()\t") {
+					return fmt.Errorf("origin %q of permissions policy feature %s is invalid", origin, f.name)
+				}
+			default:
+				return fmt.Errorf("origin %q of permissions policy feature %s is invalid", origin, f.name)
+			}
+		}
+	}
+	return nil
+}
+
+// String returns the header value in structured field syntax, e.g.
+// camera=(), fullscreen=(self "https://player.example.com").
+func (p PermissionsPolicy) String() string {
+	var b strings.Builder
+	for i, f := range p.features {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.name)
+		b.WriteByte('=')
+		if len(f.allowlist) == 1 && f.allowlist[0] == AllowAll {
+			b.WriteString(AllowAll)
+			continue
+		}
+		b.WriteByte('(')
+		for j, origin := range f.allowlist {
+			if j > 0 {
+				b.WriteByte(' ')
+			}
+			if origin == AllowSelf || origin == "src" {
+				b.WriteString(origin)
+			} else {
+				b.WriteString(`"` + origin + `"`)
+			}
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// ParsePermissionsPolicy parses a Permissions-Policy header value.
+func ParsePermissionsPolicy(value string) (PermissionsPolicy, error) {
+	p := NewPermissionsPolicy()
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		name, allowlist, ok := strings.Cut(member, "=")
+		if !ok {
+			return p, fmt.Errorf("permissions policy feature %s has no allowlist", member)
+		}
+		allowlist = strings.TrimSpace(allowlist)
+		if allowlist == AllowAll {
+			p = p.Allow(strings.TrimSpace(name), AllowAll)
+			continue
+		}
+		inner, hasPrefix := strings.CutPrefix(allowlist, "(")
+		inner, hasSuffix := strings.CutSuffix(inner, ")")
+		if !hasPrefix || !hasSuffix {
+			return p, fmt.Errorf("allowlist %s of permissions policy feature %s is invalid", allowlist, name)
+		}
+		var origins []string
+		for _, origin := range strings.Fields(inner) {
+			origins = append(origins, strings.Trim(origin, `"`))
+		}
+		p = p.Allow(strings.TrimSpace(name), origins...)
+	}
+	return p, p.Validate()
+}
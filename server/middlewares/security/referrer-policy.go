@@ -1,5 +1,19 @@
 package security
 
+// ReferrerPolicy is a value of the Referrer-Policy header.
+type ReferrerPolicy string
+
+const (
+	ReferrerNoReferrer                  ReferrerPolicy = "no-referrer"
+	ReferrerNoReferrerWhenDowngrade     ReferrerPolicy = "no-referrer-when-downgrade"
+	ReferrerOrigin                      ReferrerPolicy = "origin"
+	ReferrerOriginWhenCrossOrigin       ReferrerPolicy = "origin-when-cross-origin"
+	ReferrerSameOrigin                  ReferrerPolicy = "same-origin"
+	ReferrerStrictOrigin                ReferrerPolicy = "strict-origin"
+	ReferrerStrictOriginWhenCrossOrigin ReferrerPolicy = "strict-origin-when-cross-origin"
+	ReferrerUnsafeURL                   ReferrerPolicy = "unsafe-url"
+)
+
 func NewReferrerPolicy() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("Referrer-Policy", "same-origin")
+	return NewKeyValuePairProvider("Referrer-Policy", string(ReferrerSameOrigin))
 }
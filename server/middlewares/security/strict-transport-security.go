@@ -1,5 +1,82 @@
 package security
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// preloadMinMaxAge is the minimum max-age accepted by the HSTS preload list.
+const preloadMinMaxAge = 365 * 24 * time.Hour
+
+// StrictTransportSecurity configures the Strict-Transport-Security header, which tells
+// browsers to only connect via HTTPS for MaxAge.
+type StrictTransportSecurity struct {
+	MaxAge            time.Duration
+	IncludeSubDomains bool
+	// Preload allows the domain to be added to the HSTS preload list of browsers. It
+	// requires IncludeSubDomains and a MaxAge of at least one year.
+	Preload bool
+}
+
 func NewStrictTransportSecurityPolicy() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
+	return NewKeyValuePairProvider("Strict-Transport-Security", StrictTransportSecurity{
+		MaxAge:            preloadMinMaxAge,
+		IncludeSubDomains: true,
+		Preload:           true,
+	}.String())
+}
+
+// ParseStrictTransportSecurity parses a Strict-Transport-Security header value.
+func ParseStrictTransportSecurity(value string) (StrictTransportSecurity, error) {
+	var hsts StrictTransportSecurity
+	hasMaxAge := false
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "":
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(arg, `"`), 10, 64)
+			if err != nil || seconds < 0 {
+				return hsts, fmt.Errorf("max-age %s of strict transport security is invalid", arg)
+			}
+			hsts.MaxAge = time.Duration(seconds) * time.Second
+			hasMaxAge = true
+		case "includesubdomains":
+			hsts.IncludeSubDomains = true
+		case "preload":
+			hsts.Preload = true
+		default:
+			return hsts, fmt.Errorf("directive %s of strict transport security is unknown", name)
+		}
+	}
+	if !hasMaxAge {
+		return hsts, errors.New("strict transport security requires max-age")
+	}
+	return hsts, hsts.Validate()
+}
+
+// Validate returns an error if the directives contradict each other.
+func (h StrictTransportSecurity) Validate() error {
+	if h.MaxAge < 0 {
+		return errors.New("max-age of strict transport security must not be negative")
+	}
+	if h.Preload && (!h.IncludeSubDomains || h.MaxAge < preloadMinMaxAge) {
+		return errors.New("preload of strict transport security requires includeSubDomains and a max-age of at least one year")
+	}
+	return nil
+}
+
+// String returns the header value.
+func (h StrictTransportSecurity) String() string {
+	value := "max-age=" + strconv.FormatInt(int64(h.MaxAge/time.Second), 10)
+	if h.IncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if h.Preload {
+		value += "; preload"
+	}
+	return value
 }
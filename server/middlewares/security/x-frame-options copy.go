@@ -1,5 +1,13 @@
 package security
 
+// FrameOptions is a value of the X-Frame-Options header.
+type FrameOptions string
+
+const (
+	FrameDeny       FrameOptions = "DENY"
+	FrameSameOrigin FrameOptions = "SAMEORIGIN"
+)
+
 func NewXFrameOptions() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("X-Frame-Options", "DENY")
+	return NewKeyValuePairProvider("X-Frame-Options", string(FrameDeny))
 }
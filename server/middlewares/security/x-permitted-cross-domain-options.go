@@ -1,5 +1,15 @@
 package security
 
+// PermittedCrossDomainPolicies is a value of the X-Permitted-Cross-Domain-Policies header.
+type PermittedCrossDomainPolicies string
+
+const (
+	CrossDomainNone          PermittedCrossDomainPolicies = "none"
+	CrossDomainMasterOnly    PermittedCrossDomainPolicies = "master-only"
+	CrossDomainByContentType PermittedCrossDomainPolicies = "by-content-type"
+	CrossDomainAll           PermittedCrossDomainPolicies = "all"
+)
+
 func NewXPermittedCrossDomainOptions() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("X-Permitted-Cross-Domain-Policies", "none")
+	return NewKeyValuePairProvider("X-Permitted-Cross-Domain-Policies", string(CrossDomainNone))
 }
@@ -1,5 +1,15 @@
 package security
 
+// XSSProtection is a value of the X-XSS-Protection header. Current browsers ignore the
+// header, XSSProtectionDisabled turns off the filter of older browsers, which could be
+// abused to leak information.
+type XSSProtection string
+
+const (
+	XSSProtectionDisabled XSSProtection = "0"
+	XSSProtectionBlock    XSSProtection = "1; mode=block"
+)
+
 func NewXssProtection() HeaderKeyValueProvider {
-	return NewKeyValuePairProvider("X-XSS-Protection", "1; mode=block")
+	return NewKeyValuePairProvider("X-XSS-Protection", string(XSSProtectionBlock))
 }
@@ -0,0 +1,140 @@
+// Package std exports the middlewares of go-api-kit as func(http.Handler) http.Handler,
+// so they can be used in chains built with the standard library.
+package std
+
+import (
+	"github.com/stfsy/go-api-kit/server/adapter"
+	"github.com/stfsy/go-api-kit/server/metrics"
+	"github.com/stfsy/go-api-kit/server/middlewares"
+	"github.com/stfsy/go-api-kit/server/middlewares/ratelimit"
+	"github.com/stfsy/go-api-kit/server/tracing"
+)
+
+// AccessLog logs each request. See middlewares.AccessLogMiddleware.
+func AccessLog() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewAccessLog())
+}
+
+// SecurityHeaders sets the security headers. See middlewares.SecurityHeadersMiddleware.
+func SecurityHeaders() (adapter.StdMiddleware, error) {
+	m, err := middlewares.NewSecurityHeadersMiddleware()
+	if err != nil {
+		return nil, err
+	}
+	return adapter.ToStd(m), nil
+}
+
+// NoCacheHeaders prevents caching. See middlewares.NoCacheHeadersMiddleware.
+//
+// Deprecated: use CachePolicy.
+func NoCacheHeaders() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewNoCacheHeadersMiddleware())
+}
+
+// CachePolicy applies the cache policies of routes. See middlewares.CachePolicyMiddleware.
+func CachePolicy() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewCachePolicyMiddleware())
+}
+
+// RequireHTTP11 rejects HTTP/1.0 requests. See middlewares.RequireHTTP11Middleware.
+func RequireHTTP11() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewRequireHTTP11Middleware())
+}
+
+// RequireMaxBodyLength limits the request body. See middlewares.RequireMaxBodyLengthMiddleware.
+func RequireMaxBodyLength() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewRequireMaxBodyLengthMiddleware())
+}
+
+// RequireContentLengthOrTransferEncoding rejects write requests without a length.
+// See middlewares.RequireContentLengthOrTransferEncodingMiddleware.
+func RequireContentLengthOrTransferEncoding() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewRequireContentLengthOrTransferEncodingMiddleware())
+}
+
+// RequireContentType rejects write requests with another content type.
+// See middlewares.RequireContentTypeMiddleware.
+func RequireContentType(allowedContentType string) adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewRequireContentTypeMiddleware(allowedContentType))
+}
+
+// Timeout responds with 503 if the handler does not respond in time. See middlewares.TimeoutMiddleware.
+func Timeout() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewTimeoutMiddleware())
+}
+
+// RequestId assigns an ID to each request. See middlewares.RequestIdMiddleware.
+func RequestId() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewRequestIdMiddleware())
+}
+
+// Tracing starts a server span for each request. See middlewares.TracingMiddleware.
+func Tracing(exporter tracing.Exporter) adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewTracingMiddleware(exporter))
+}
+
+// Metrics records request metrics in registry. See middlewares.MetricsMiddleware.
+func Metrics(registry *metrics.Registry) (adapter.StdMiddleware, error) {
+	m, err := middlewares.NewMetricsMiddleware(registry)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.ToStd(m), nil
+}
+
+// Recovery recovers from panics with a problem+json response. See middlewares.RecoveryMiddleware.
+func Recovery() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewRecoveryMiddleware())
+}
+
+// TrustedProxies resolves the client behind trusted proxies. See middlewares.TrustedProxiesMiddleware.
+func TrustedProxies() (adapter.StdMiddleware, error) {
+	m, err := middlewares.NewTrustedProxiesMiddleware()
+	if err != nil {
+		return nil, err
+	}
+	return adapter.ToStd(m), nil
+}
+
+// IPFilter rejects clients by IP address. See middlewares.IPFilterMiddleware.
+func IPFilter() (adapter.StdMiddleware, error) {
+	m, err := middlewares.NewIPFilterMiddleware()
+	if err != nil {
+		return nil, err
+	}
+	return adapter.ToStd(m), nil
+}
+
+// Compression compresses responses. See middlewares.CompressionMiddleware.
+func Compression() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewCompressionMiddleware())
+}
+
+// Decompression decompresses request bodies. See middlewares.DecompressionMiddleware.
+func Decompression() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewDecompressionMiddleware())
+}
+
+// ConditionalRequests adds ETags and answers conditional requests. See middlewares.ConditionalRequestsMiddleware.
+func ConditionalRequests() adapter.StdMiddleware {
+	return adapter.ToStd(middlewares.NewConditionalRequestsMiddleware())
+}
+
+// RateLimit limits the requests per client. See ratelimit.Middleware.
+func RateLimit() (adapter.StdMiddleware, error) {
+	m, err := ratelimit.NewMiddleware()
+	if err != nil {
+		return nil, err
+	}
+	return adapter.ToStd(m), nil
+}
+
+// ConcurrencyLimit caps the requests in flight with limit or, if limit is nil, with the
+// static limit set by API_KIT_CONCURRENCY_LIMIT. See middlewares.ConcurrencyLimitMiddleware.
+func ConcurrencyLimit(limit middlewares.ConcurrencyLimit) adapter.StdMiddleware {
+	m := middlewares.NewConcurrencyLimitMiddleware()
+	if limit != nil {
+		m.SetLimit(limit)
+	}
+	return adapter.ToStd(m)
+}
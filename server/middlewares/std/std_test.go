@@ -0,0 +1,42 @@
+package std
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/adapter"
+	"github.com/stfsy/go-api-kit/server/middlewares"
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestStdMiddlewares_WorkInStdChain(t *testing.T) {
+	assert := a.New(t)
+
+	securityHeaders, err := SecurityHeaders()
+	assert.NoError(err)
+	rateLimit, err := RateLimit()
+	assert.NoError(err)
+	h := adapter.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		AccessLog(),
+		securityHeaders,
+		rateLimit,
+		ConcurrencyLimit(middlewares.StaticLimit(1)),
+		NoCacheHeaders(),
+		RequireHTTP11(),
+		RequireMaxBodyLength(),
+		RequireContentLengthOrTransferEncoding(),
+		RequireContentType("application/json"),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("DENY", rec.Header().Get("X-Frame-Options"))
+	assert.NotEmpty(rec.Header().Get("Cache-Control"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+	assert.Equal(http.StatusUnsupportedMediaType, rec.Code)
+}
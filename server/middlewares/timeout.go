@@ -0,0 +1,207 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+var timeoutLogger = utils.NewLogger("timeout-middleware")
+
+// TimeoutMiddleware cancels the request context at a deadline and responds with 503
+// Service Unavailable or 504 Gateway Timeout if the handler has not written a response
+// by then. Writes of the handler after the deadline fail with http.ErrHandlerTimeout.
+// A handler that responds itself as soon as its context is canceled may win the race
+// against the timeout response.
+type TimeoutMiddleware struct {
+	// Timeout applies to routes without a Timeout option. Zero disables the timeout.
+	Timeout time.Duration
+	// Status is sent when the deadline is exceeded, either http.StatusServiceUnavailable
+	// or http.StatusGatewayTimeout.
+	Status int
+}
+
+// NewTimeoutMiddleware returns a middleware with the timeout set by API_KIT_REQUEST_TIMEOUT,
+// responding with 503 Service Unavailable.
+func NewTimeoutMiddleware() *TimeoutMiddleware {
+	return &TimeoutMiddleware{
+		Timeout: time.Duration(config.Get().RequestTimeout) * time.Second,
+		Status:  http.StatusServiceUnavailable,
+	}
+}
+
+func (m *TimeoutMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	timeout := m.Timeout
+	if routeTimeout := route.OptionsFromContext(r.Context()).Timeout; routeTimeout > 0 {
+		timeout = routeTimeout
+	}
+	if timeout <= 0 {
+		next.ServeHTTP(rw, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	tw := &timeoutWriter{
+		rw:     rw,
+		header: rw.Header().Clone(),
+	}
+	done := make(chan struct{})
+	panicked := make(chan any, 1)
+	start := time.Now()
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+		}()
+		next.ServeHTTP(tw, r)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case p := <-panicked:
+		// re-panic in the request goroutine, so the recovery middleware can handle it
+		panic(p)
+	case <-ctx.Done():
+	}
+
+	tw.mu.Lock()
+	tw.timedOut = true
+	wroteHeader := tw.wroteHeader
+	tw.mu.Unlock()
+
+	if !wroteHeader {
+		if m.Status == http.StatusGatewayTimeout {
+			handlers.SendGatewayTimeout(rw, nil)
+		} else {
+			handlers.SendServiceUnavailable(rw, nil)
+		}
+	}
+
+	timeoutLogger.Warn("handler did not finish before the deadline",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"timeout", timeout,
+		"response_started", wroteHeader,
+	)
+
+	go func() {
+		select {
+		case <-done:
+			timeoutLogger.Warn(fmt.Sprintf("handler finished %s after the deadline", time.Since(start)-timeout),
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+		case p := <-panicked:
+			timeoutLogger.Error(fmt.Sprintf("handler panicked after the deadline: %v", p),
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+		}
+	}()
+}
+
+// timeoutWriter passes writes through to rw until the deadline. The handler gets its own
+// header map, so it cannot race with the timeout response. Like negroni.ResponseWriter it
+// reports the status and size of the response of the handler. It does not unwrap to rw,
+// because http.ResponseController would then bypass the deadline.
+type timeoutWriter struct {
+	rw          http.ResponseWriter
+	header      http.Header
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+	status      int
+	size        int
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(status)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(status int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	// informational responses do not complete the header
+	if status >= 100 && status < 200 {
+		maps.Copy(tw.rw.Header(), tw.header)
+		tw.rw.WriteHeader(status)
+		return
+	}
+
+	tw.wroteHeader = true
+	tw.status = status
+	dst := tw.rw.Header()
+	clear(dst)
+	maps.Copy(dst, tw.header)
+	tw.rw.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	n, err := tw.rw.Write(b)
+	tw.size += n
+	return n, err
+}
+
+// Flush flushes the underlying writer if it supports flushing.
+func (tw *timeoutWriter) Flush() {
+	_ = tw.FlushError()
+}
+
+// FlushError flushes the underlying writer for http.ResponseController. It returns
+// http.ErrHandlerTimeout after the deadline.
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return http.NewResponseController(tw.rw).Flush()
+}
+
+// Status returns the status code written by the handler or 0 if it did not respond yet.
+func (tw *timeoutWriter) Status() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.status
+}
+
+// Written reports whether the handler wrote the header.
+func (tw *timeoutWriter) Written() bool {
+	return tw.Status() != 0
+}
+
+// Size returns the number of body bytes written by the handler.
+func (tw *timeoutWriter) Size() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.size
+}
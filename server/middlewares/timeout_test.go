@@ -0,0 +1,188 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func newTimeoutTestHandler(m *TimeoutMiddleware, h http.HandlerFunc) *negroni.Negroni {
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(h)
+	return n
+}
+
+func TestTimeoutMiddleware_RespondsWithServiceUnavailable(t *testing.T) {
+	assert := a.New(t)
+
+	release := make(chan struct{})
+	writeErr := make(chan error, 1)
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: 10 * time.Millisecond, Status: http.StatusServiceUnavailable},
+		func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Header().Set("X-Handler", "late")
+			_, err := w.Write([]byte("late"))
+			writeErr <- err
+		})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	close(release)
+
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(handlers.ContentTypeProblemJson, rec.Header().Get("Content-Type"))
+	assert.Empty(rec.Header().Get("X-Handler"))
+
+	var payload handlers.HttpError
+	assert.NoError(json.NewDecoder(rec.Body).Decode(&payload))
+	assert.Equal("Service Unavailable", payload.Title)
+
+	assert.ErrorIs(<-writeErr, http.ErrHandlerTimeout)
+}
+
+func TestTimeoutMiddleware_RespondsWithGatewayTimeout(t *testing.T) {
+	release := make(chan struct{})
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: 10 * time.Millisecond, Status: http.StatusGatewayTimeout},
+		func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	close(release)
+
+	a.Equal(t, http.StatusGatewayTimeout, rec.Code)
+}
+
+func TestTimeoutMiddleware_KeepsStartedResponse(t *testing.T) {
+	assert := a.New(t)
+
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: 10 * time.Millisecond, Status: http.StatusServiceUnavailable},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("partial"))
+			<-r.Context().Done()
+		})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal("partial", rec.Body.String())
+}
+
+func TestTimeoutMiddleware_PassesFastResponses(t *testing.T) {
+	assert := a.New(t)
+
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: time.Second, Status: http.StatusServiceUnavailable},
+		func(w http.ResponseWriter, r *http.Request) {
+			_, hasDeadline := r.Context().Deadline()
+			assert.True(hasDeadline)
+			w.Header().Set("X-Handler", "fast")
+			w.WriteHeader(http.StatusCreated)
+		})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(http.StatusCreated, rec.Code)
+	assert.Equal("fast", rec.Header().Get("X-Handler"))
+}
+
+func TestTimeoutMiddleware_ReportsStatus(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: time.Second, Status: http.StatusServiceUnavailable},
+		func(w http.ResponseWriter, r *http.Request) {
+			res, ok := w.(interface {
+				Status() int
+				Written() bool
+				Size() int
+			})
+			assert.True(ok)
+			assert.False(res.Written())
+
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("ok"))
+			assert.Equal(http.StatusAccepted, res.Status())
+			assert.True(res.Written())
+			assert.Equal(2, res.Size())
+
+			_, ok = w.(interface{ Unwrap() http.ResponseWriter })
+			assert.False(ok, "does not expose the underlying writer")
+		})
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(http.StatusAccepted, rec.Code)
+}
+
+func TestTimeoutMiddleware_FlushFailsAfterDeadline(t *testing.T) {
+	assert := a.New(t)
+
+	flushed := make(chan error, 2)
+	timedOut := make(chan struct{})
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: 10 * time.Millisecond, Status: http.StatusServiceUnavailable},
+		func(w http.ResponseWriter, r *http.Request) {
+			flushed <- http.NewResponseController(w).Flush()
+			<-timedOut
+			flushed <- http.NewResponseController(w).Flush()
+		})
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	close(timedOut)
+
+	assert.NoError(<-flushed)
+	assert.ErrorIs(<-flushed, http.ErrHandlerTimeout)
+	assert.True(rec.Flushed)
+}
+
+func TestTimeoutMiddleware_UsesRouteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Status: http.StatusServiceUnavailable},
+		func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{
+		Options: route.Options{Timeout: 10 * time.Millisecond},
+	}))
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+
+	a.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestTimeoutMiddleware_DisabledWithoutTimeout(t *testing.T) {
+	n := newTimeoutTestHandler(&TimeoutMiddleware{}, func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		a.False(t, hasDeadline)
+	})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	a.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestTimeoutMiddleware_RepanicsInRequestGoroutine(t *testing.T) {
+	n := newTimeoutTestHandler(&TimeoutMiddleware{Timeout: time.Second}, func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	a.PanicsWithValue(t, "boom", func() {
+		n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/server/requestid"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/server/tracing"
+	"github.com/urfave/negroni/v3"
+)
+
+// TracingMiddleware starts a server span for each request. A valid traceparent header
+// makes the span a child of the caller's span, an invalid one starts a new trace. The
+// span is named after the matched ServeMux pattern and stored in the request context,
+// so handlers can start child spans with tracing.Start. Responses with a 5xx status
+// set the status of the span to error.
+type TracingMiddleware struct {
+	tracer *tracing.Tracer
+}
+
+// NewTracingMiddleware returns a middleware that hands ended spans to exporter.
+func NewTracingMiddleware(exporter tracing.Exporter) *TracingMiddleware {
+	return &TracingMiddleware{tracer: tracing.NewTracer(exporter)}
+}
+
+func (m *TracingMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx := r.Context()
+	if sc, err := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); err == nil {
+		sc.TraceState = tracing.ValidateTracestate(strings.Join(r.Header.Values(tracing.TracestateHeader), ","))
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+	}
+
+	pattern := ""
+	if resolved := route.FromContext(ctx); resolved != nil {
+		pattern = resolved.Pattern
+	}
+
+	ctx, span := m.tracer.Start(ctx, spanName(r.Method, pattern), tracing.SpanKindServer)
+	defer span.End()
+
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("url.path", r.URL.Path)
+	if pattern != "" {
+		span.SetAttribute("http.route", pattern)
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		span.SetAttribute("request.id", id)
+	}
+
+	next(rw, r.WithContext(ctx))
+
+	status := 0
+	if res, ok := rw.(negroni.ResponseWriter); ok {
+		status = res.Status()
+	}
+	span.SetAttribute("http.response.status_code", status)
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(tracing.StatusError, http.StatusText(status))
+	}
+}
+
+// spanName returns "METHOD pattern" or only the method if no pattern matched, which
+// keeps the number of distinct span names low.
+func spanName(method string, pattern string) string {
+	if pattern == "" {
+		return method
+	}
+	if strings.HasPrefix(pattern, method+" ") {
+		return pattern
+	}
+	return method + " " + pattern
+}
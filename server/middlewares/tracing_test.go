@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/server/tracing"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+type recordingSpanExporter struct {
+	spans []tracing.SpanData
+}
+
+func (e *recordingSpanExporter) Export(span tracing.SpanData) error {
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+func serveTracing(req *http.Request, status int) (*recordingSpanExporter, *tracing.Span) {
+	exporter := &recordingSpanExporter{}
+	var span *tracing.Span
+	n := negroni.New()
+	n.Use(NewTracingMiddleware(exporter))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span = tracing.SpanFromContext(r.Context())
+		w.WriteHeader(status)
+	})
+	n.ServeHTTP(httptest.NewRecorder(), req)
+	return exporter, span
+}
+
+func TestTracingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	assert := a.New(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(tracing.TracestateHeader, "congo=t61rcWkgMzE")
+	req = req.WithContext(route.NewContext(req.Context(), &route.Route{Pattern: "/users/{id}"}))
+
+	exporter, span := serveTracing(req, http.StatusOK)
+
+	assert.NotNil(span)
+	assert.Len(exporter.spans, 1)
+	data := exporter.spans[0]
+	assert.Equal("GET /users/{id}", data.Name)
+	assert.Equal(tracing.SpanKindServer, data.Kind)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", data.TraceID.String())
+	assert.Equal("00f067aa0ba902b7", data.ParentSpanID.String())
+	assert.Equal("congo=t61rcWkgMzE", data.TraceState)
+	assert.Equal("/users/{id}", data.Attributes["http.route"])
+	assert.Equal(http.StatusOK, data.Attributes["http.response.status_code"])
+	assert.Equal(tracing.StatusUnset, data.Status)
+}
+
+func TestTracingMiddleware_StartsNewTraceForInvalidTraceparent(t *testing.T) {
+	assert := a.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/unknown", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-invalid")
+
+	exporter, _ := serveTracing(req, http.StatusBadGateway)
+
+	assert.Len(exporter.spans, 1)
+	data := exporter.spans[0]
+	assert.Equal("POST", data.Name)
+	assert.True(data.TraceID.IsValid())
+	assert.False(data.ParentSpanID.IsValid())
+	assert.Equal(tracing.StatusError, data.Status)
+}
+
+func TestSpanName(t *testing.T) {
+	assert := a.New(t)
+
+	assert.Equal("GET /users", spanName(http.MethodGet, "GET /users"))
+	assert.Equal("HEAD /users", spanName(http.MethodHead, "/users"))
+	assert.Equal("GET", spanName(http.MethodGet, ""))
+}
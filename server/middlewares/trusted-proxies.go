@@ -0,0 +1,205 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/clientip"
+)
+
+// Headers that carry the client address, see TrustedProxiesOptions.Header.
+const (
+	ForwardedHeaderXForwardedFor = "x-forwarded-for"
+	ForwardedHeaderForwarded     = "forwarded"
+)
+
+// TrustedProxiesOptions configure a TrustedProxiesMiddleware.
+type TrustedProxiesOptions struct {
+	// CIDRs are the ranges of trusted proxies, e.g. 10.0.0.0/8. Single addresses are accepted, too.
+	CIDRs []string
+	// Header is the header the proxies append the client address to: ForwardedHeaderXForwardedFor
+	// or ForwardedHeaderForwarded. Defaults to X-Forwarded-For.
+	Header string
+}
+
+// TrustedProxiesMiddleware resolves the client of requests that passed through trusted
+// proxies and stores it in the request context, read it with clientip.FromRequest. The
+// client IP is the right-most address in the configured Forwarded (RFC 7239) or
+// X-Forwarded-For header that is not a trusted proxy, the scheme is taken from the proto
+// parameter or X-Forwarded-Proto. Headers of peers that are not trusted are ignored, so
+// clients cannot spoof their address.
+//
+// Only the configured header is read. Most proxies pass the other header of the client
+// through unchanged, so reading it would let clients choose their address.
+type TrustedProxiesMiddleware struct {
+	proxies   []netip.Prefix
+	forwarded bool
+}
+
+// NewTrustedProxiesMiddleware returns a middleware that trusts proxies with an address in
+// one of the CIDR ranges set by API_KIT_TRUSTED_PROXIES and read the header set by
+// API_KIT_TRUSTED_PROXIES_HEADER. It returns an error if a range or the header is invalid.
+func NewTrustedProxiesMiddleware() (*TrustedProxiesMiddleware, error) {
+	c := config.Get()
+	return NewTrustedProxiesMiddlewareWithOptions(TrustedProxiesOptions{
+		CIDRs:  c.TrustedProxies,
+		Header: c.TrustedProxiesHeader,
+	})
+}
+
+// NewTrustedProxiesMiddlewareWithCIDRs returns a middleware that trusts proxies with an
+// address in one of the CIDR ranges, e.g. 10.0.0.0/8, and read X-Forwarded-For. Single
+// addresses are accepted, too.
+func NewTrustedProxiesMiddlewareWithCIDRs(cidrs ...string) (*TrustedProxiesMiddleware, error) {
+	return NewTrustedProxiesMiddlewareWithOptions(TrustedProxiesOptions{CIDRs: cidrs})
+}
+
+// NewTrustedProxiesMiddlewareWithOptions returns a middleware configured by options. It
+// returns an error if a range or the header is invalid.
+func NewTrustedProxiesMiddlewareWithOptions(options TrustedProxiesOptions) (*TrustedProxiesMiddleware, error) {
+	prefixes, err := parsePrefixes(options.CIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &TrustedProxiesMiddleware{proxies: prefixes}
+	switch strings.ToLower(options.Header) {
+	case "", ForwardedHeaderXForwardedFor:
+	case ForwardedHeaderForwarded:
+		m.forwarded = true
+	default:
+		return nil, fmt.Errorf("forwarded header %s is not supported", options.Header)
+	}
+	return m, nil
+}
+
+func (m *TrustedProxiesMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	client := clientip.FromRequest(r)
+	if m.trusted(client.IP) {
+		client = m.resolve(r, client)
+	}
+	next(rw, r.WithContext(clientip.NewContext(r.Context(), client)))
+}
+
+func (m *TrustedProxiesMiddleware) trusted(addr netip.Addr) bool {
+	return addr.IsValid() && containsAddr(m.proxies, addr)
+}
+
+type forwardedHop struct {
+	addr  netip.Addr
+	proto string
+}
+
+// resolve walks the hops from right to left, starting at the trusted peer.
+func (m *TrustedProxiesMiddleware) resolve(r *http.Request, peer clientip.Client) clientip.Client {
+	var hops []forwardedHop
+	if m.forwarded {
+		hops = parseForwarded(r.Header.Values("Forwarded"))
+	} else {
+		hops = parseXForwardedFor(r.Header.Values("X-Forwarded-For"), r.Header.Values("X-Forwarded-Proto"))
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if !hop.addr.IsValid() {
+			// obfuscated or malformed hops end the chain that can be trusted
+			break
+		}
+		// the proto is set by the trusted proxy that appended the hop
+		if hop.proto == "http" || hop.proto == "https" {
+			client.Scheme = hop.proto
+		}
+		client.IP = hop.addr
+		if !m.trusted(hop.addr) {
+			break
+		}
+	}
+	return client
+}
+
+// parseForwarded returns the for and proto parameters of all elements of the Forwarded
+// headers, see RFC 7239.
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			var hop forwardedHop
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				val = strings.Trim(val, `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					hop.addr = parseForwardedNode(val)
+				case "proto":
+					hop.proto = strings.ToLower(val)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseForwardedNode parses a node like 192.0.2.60, 192.0.2.60:4711 or "[2001:db8::1]:4711".
+func parseForwardedNode(node string) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func parseXForwardedFor(forValues []string, protoValues []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range forValues {
+		for _, node := range strings.Split(value, ",") {
+			hops = append(hops, forwardedHop{addr: parseForwardedNode(strings.TrimSpace(node))})
+		}
+	}
+
+	// X-Forwarded-Proto lists one proto per proxy, the last one belongs to the last hop
+	var protos []string
+	for _, value := range protoValues {
+		for _, proto := range strings.Split(value, ",") {
+			protos = append(protos, strings.ToLower(strings.TrimSpace(proto)))
+		}
+	}
+	for i := 0; i < len(protos) && i < len(hops); i++ {
+		hops[len(hops)-1-i].proto = protos[len(protos)-1-i]
+	}
+	return hops
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %s: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %s: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/clientip"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func serveTrustedProxies(t *testing.T, header string, remoteAddr string, headers map[string]string) clientip.Client {
+	m, err := NewTrustedProxiesMiddlewareWithOptions(TrustedProxiesOptions{
+		CIDRs:  []string{"10.0.0.0/8", "192.0.2.1"},
+		Header: header,
+	})
+	a.NoError(t, err)
+
+	var client clientip.Client
+	n := negroni.New()
+	n.Use(m)
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client = clientip.FromRequest(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	n.ServeHTTP(httptest.NewRecorder(), req)
+	return client
+}
+
+func TestTrustedProxiesMiddleware(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header     string
+		remoteAddr string
+		headers    map[string]string
+		ip         string
+		scheme     string
+	}{
+		"untrusted peer": {
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"},
+			ip:         "203.0.113.5",
+			scheme:     "http",
+		},
+		"x-forwarded-for": {
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 10.0.0.3", "X-Forwarded-Proto": "https"},
+			ip:         "198.51.100.1",
+			scheme:     "https",
+		},
+		"spoofed chain": {
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"},
+			ip:         "198.51.100.1",
+			scheme:     "http",
+		},
+		"forwarded": {
+			header:     ForwardedHeaderForwarded,
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"Forwarded": `for="[2001:db8::7]:4711";proto=https, for=10.0.0.3`},
+			ip:         "2001:db8::7",
+			scheme:     "https",
+		},
+		"client forwarded ignored by default": {
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "198.51.100.7"},
+			ip:         "198.51.100.7",
+			scheme:     "http",
+		},
+		"client x-forwarded-for ignored with forwarded": {
+			header:     ForwardedHeaderForwarded,
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": "for=198.51.100.2", "X-Forwarded-For": "1.2.3.4"},
+			ip:         "198.51.100.2",
+			scheme:     "http",
+		},
+		"missing configured header": {
+			header:     ForwardedHeaderForwarded,
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			ip:         "10.0.0.2",
+			scheme:     "http",
+		},
+		"obfuscated hop": {
+			header:     ForwardedHeaderForwarded,
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"Forwarded": "for=198.51.100.2, for=_hidden, for=10.0.0.3"},
+			ip:         "10.0.0.3",
+			scheme:     "http",
+		},
+		"only proxies": {
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.4, 10.0.0.3"},
+			ip:         "10.0.0.4",
+			scheme:     "http",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := serveTrustedProxies(t, tc.header, tc.remoteAddr, tc.headers)
+
+			a.Equal(t, tc.ip, client.IP.String())
+			a.Equal(t, tc.scheme, client.Scheme)
+		})
+	}
+}
+
+func TestNewTrustedProxiesMiddlewareWithCIDRs_RejectsInvalidRanges(t *testing.T) {
+	_, err := NewTrustedProxiesMiddlewareWithCIDRs("10.0.0.0/33")
+
+	a.ErrorContains(t, err, "invalid CIDR range 10.0.0.0/33")
+}
+
+func TestNewTrustedProxiesMiddlewareWithOptions_RejectsUnknownHeader(t *testing.T) {
+	_, err := NewTrustedProxiesMiddlewareWithOptions(TrustedProxiesOptions{CIDRs: []string{"10.0.0.0/8"}, Header: "x-real-ip"})
+
+	a.EqualError(t, err, "forwarded header x-real-ip is not supported")
+}
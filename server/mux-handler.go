@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/server/router"
+)
+
+// createRouteResolver resolves the route of each request before the middleware stack runs,
+// so middlewares can apply the options of the route.
+func createRouteResolver(rt *router.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ctx := route.NewContext(r.Context(), rt.Resolve(r))
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+// createMuxHandler wraps mux so that the replies the mux generates itself for requests
+// without a matching pattern are rendered as problem+json instead of text/plain. OPTIONS
+// requests for registered paths are answered with the allowed methods.
+func createMuxHandler(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var h http.Handler
+		var pattern string
+		if resolved := route.FromContext(r.Context()); resolved != nil {
+			pattern = resolved.Pattern
+		} else {
+			h, pattern = mux.Handler(r)
+		}
+		if pattern != "" {
+			// the mux is called again instead of h, because only the mux populates
+			// r.Pattern and the path values
+			mux.ServeHTTP(rw, r)
+			return
+		}
+
+		// without a pattern, h is one of the mux's own error handlers. Run it against a
+		// recorder to learn the status and the allowed methods it computed.
+		if h == nil {
+			h, _ = mux.Handler(r)
+		}
+		rec := &muxErrorRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+
+		if rec.status != http.StatusMethodNotAllowed {
+			handlers.SendNotFound(rw, nil)
+			return
+		}
+
+		allow := allowedMethods(rec.header.Get("Allow"))
+		rw.Header().Set("Allow", allow)
+		if r.Method == http.MethodOptions {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		handlers.SendMethodNotAllowed(rw, nil)
+	})
+}
+
+// allowedMethods adds OPTIONS to the methods computed by the mux, because OPTIONS
+// is answered for every registered path.
+func allowedMethods(allow string) string {
+	if allow == "" {
+		return http.MethodOptions
+	}
+	for _, method := range strings.Split(allow, ",") {
+		if strings.TrimSpace(method) == http.MethodOptions {
+			return allow
+		}
+	}
+	return allow + ", " + http.MethodOptions
+}
+
+// muxErrorRecorder captures the status and headers of the mux's error handlers and
+// discards their text/plain body.
+type muxErrorRecorder struct {
+	header http.Header
+	status int
+}
+
+func (r *muxErrorRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *muxErrorRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *muxErrorRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return len(b), nil
+}
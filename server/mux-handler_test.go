@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/handlers"
+	a "github.com/stretchr/testify/assert"
+)
+
+func newTestMuxHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	return createMuxHandler(mux)
+}
+
+func decodeProblem(t *testing.T, res *http.Response) handlers.HttpError {
+	var payload handlers.HttpError
+	err := json.NewDecoder(res.Body).Decode(&payload)
+	a.Nil(t, err)
+	return payload
+}
+
+func TestMuxHandler_ServesMatchingPatternWithPathValues(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	newTestMuxHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("42", rec.Body.String())
+}
+
+func TestMuxHandler_RendersNotFoundAsProblem(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	newTestMuxHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	res := rec.Result()
+
+	assert.Equal(http.StatusNotFound, res.StatusCode)
+	assert.Equal(handlers.ContentTypeProblemJson, res.Header.Get("Content-Type"))
+	assert.Equal("Not Found", decodeProblem(t, res).Title)
+}
+
+func TestMuxHandler_RendersMethodNotAllowedWithAllowHeader(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	newTestMuxHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/items", nil))
+	res := rec.Result()
+
+	assert.Equal(http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(handlers.ContentTypeProblemJson, res.Header.Get("Content-Type"))
+	assert.Equal("POST, OPTIONS", res.Header.Get("Allow"))
+	assert.Equal("Method Not Allowed", decodeProblem(t, res).Title)
+}
+
+func TestMuxHandler_AllowIncludesHeadForGet(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	newTestMuxHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/items/1", nil))
+
+	assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal("GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+}
+
+func TestMuxHandler_AnswersOptions(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	newTestMuxHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/items", nil))
+
+	assert.Equal(http.StatusNoContent, rec.Code)
+	assert.Equal("POST, OPTIONS", rec.Header().Get("Allow"))
+	assert.Empty(rec.Body.String())
+}
+
+func TestMuxHandler_OptionsForUnknownPathIsNotFound(t *testing.T) {
+	assert := a.New(t)
+
+	rec := httptest.NewRecorder()
+	newTestMuxHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/unknown", nil))
+
+	assert.Equal(http.StatusNotFound, rec.Code)
+	assert.Empty(rec.Header().Get("Allow"))
+}
+
+func TestAllowedMethods_DoesNotDuplicateOptions(t *testing.T) {
+	assert := a.New(t)
+
+	assert.Equal("OPTIONS", allowedMethods(""))
+	assert.Equal("GET, OPTIONS", allowedMethods("GET, OPTIONS"))
+}
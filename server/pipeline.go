@@ -0,0 +1,341 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/middlewares"
+	"github.com/stfsy/go-api-kit/server/middlewares/ratelimit"
+	cors "github.com/stfsy/go-cors"
+	"github.com/urfave/negroni/v3"
+)
+
+// Names of the middlewares of the default pipeline, in their default order.
+const (
+	MiddlewareRequestId       = "request-id"
+	MiddlewareTrustedProxies  = "trusted-proxies"
+	MiddlewareTracing         = "tracing"
+	MiddlewareMetrics         = "metrics"
+	MiddlewareRecovery        = "recovery"
+	MiddlewareAccessLog       = "access-log"
+	MiddlewareSecurityHeaders = "security-headers"
+	MiddlewareNoCache         = "no-cache"
+	MiddlewareCompression     = "compression"
+	MiddlewareIPFilter        = "ip-filter"
+	MiddlewareRateLimit       = "rate-limit"
+	MiddlewareConcurrency     = "concurrency-limit"
+	MiddlewareTimeout         = "timeout"
+	MiddlewareRequireHTTP11   = "require-http-1-1"
+	MiddlewareMaxBodyLength   = "max-body-length"
+	MiddlewareCors            = "cors"
+	MiddlewareContentLength   = "content-length"
+	MiddlewareContentType     = "content-type"
+	MiddlewareDecompression   = "decompression"
+	MiddlewareConditional     = "conditional-requests"
+
+	// MiddlewareCachePolicy is the entry of the cache policy middleware. It keeps the name
+	// of the no-cache middleware it replaced, so existing pipeline callbacks still find it.
+	MiddlewareCachePolicy = MiddlewareNoCache
+)
+
+// PipelineEntry is a named middleware of the pipeline. Entries without a handler are
+// kept in the pipeline, so they can be referenced by name, but are not run.
+type PipelineEntry struct {
+	Name    string
+	Handler negroni.Handler
+}
+
+// Pipeline is the ordered list of global middlewares that run before the mux.
+type Pipeline struct {
+	entries []PipelineEntry
+}
+
+// NewPipeline returns a pipeline with the given entries.
+func NewPipeline(entries ...PipelineEntry) *Pipeline {
+	return &Pipeline{entries: entries}
+}
+
+// DefaultPipeline returns the default middlewares in their default order. The cors entry
+// has no handler unless sc.CorsConfig is set, the rate-limit and concurrency-limit entries
+// have no handler unless a limit is configured, the tracing entry has no handler unless
+// sc.TraceExporter is set and the metrics entry has no handler unless sc.Metrics is set.
+// The trusted-proxies and ip-filter entries have no handler unless ranges are configured.
+//
+// It returns an error if the configuration of a middleware is invalid.
+func DefaultPipeline(sc *ServerConfig) (*Pipeline, error) {
+	var corsHandler negroni.Handler
+	if sc.CorsConfig != nil {
+		corsHandler = cors.New(*sc.CorsConfig)
+	}
+
+	var rateLimitHandler negroni.Handler
+	if sc.RateLimiter != nil {
+		rateLimitHandler = sc.RateLimiter
+	} else if config.Get().RateLimit > 0 {
+		m, err := ratelimit.NewMiddleware()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit configuration: %w", err)
+		}
+		rateLimitHandler = m
+	}
+
+	var concurrencyHandler negroni.Handler
+	if sc.ConcurrencyLimit != nil {
+		concurrency := middlewares.NewConcurrencyLimitMiddleware()
+		concurrency.SetLimit(sc.ConcurrencyLimit)
+		concurrencyHandler = concurrency
+	} else if config.Get().ConcurrencyLimit > 0 {
+		concurrencyHandler = middlewares.NewConcurrencyLimitMiddleware()
+	}
+
+	var tracingHandler negroni.Handler
+	if sc.TraceExporter != nil {
+		tracingHandler = middlewares.NewTracingMiddleware(sc.TraceExporter)
+	}
+
+	var metricsHandler negroni.Handler
+	if sc.Metrics != nil {
+		m, err := middlewares.NewMetricsMiddleware(sc.Metrics)
+		if err != nil {
+			return nil, fmt.Errorf("unable to register request metrics: %w", err)
+		}
+		metricsHandler = m
+	}
+
+	var trustedProxiesHandler negroni.Handler
+	if len(sc.TrustedProxies) > 0 {
+		header := sc.TrustedProxiesHeader
+		if header == "" {
+			header = config.Get().TrustedProxiesHeader
+		}
+		m, err := middlewares.NewTrustedProxiesMiddlewareWithOptions(middlewares.TrustedProxiesOptions{
+			CIDRs:  sc.TrustedProxies,
+			Header: header,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+		}
+		trustedProxiesHandler = m
+	} else if len(config.Get().TrustedProxies) > 0 {
+		m, err := middlewares.NewTrustedProxiesMiddleware()
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+		}
+		trustedProxiesHandler = m
+	}
+
+	var ipFilterHandler negroni.Handler
+	if len(sc.IPAllowList) > 0 || len(sc.IPDenyList) > 0 {
+		m, err := middlewares.NewIPFilterMiddlewareWithCIDRs(sc.IPAllowList, sc.IPDenyList)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP filter: %w", err)
+		}
+		ipFilterHandler = m
+	} else if len(config.Get().IpAllowList) > 0 || len(config.Get().IpDenyList) > 0 {
+		m, err := middlewares.NewIPFilterMiddleware()
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP filter: %w", err)
+		}
+		ipFilterHandler = m
+	}
+
+	compression := middlewares.NewCompressionMiddleware()
+	if sc.Compression != nil {
+		compression = middlewares.NewCompressionMiddlewareWithOptions(*sc.Compression)
+	}
+
+	decompression := middlewares.NewDecompressionMiddleware()
+	if sc.Decompression != nil {
+		decompression = middlewares.NewDecompressionMiddlewareWithOptions(*sc.Decompression)
+	}
+
+	securityHeaders, err := createSecurityHeadersMiddleware(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	conditional := middlewares.NewConditionalRequestsMiddleware()
+	if sc.ConditionalRequests != nil {
+		conditional = middlewares.NewConditionalRequestsMiddlewareWithOptions(*sc.ConditionalRequests)
+	}
+
+	accessLog := middlewares.NewAccessLog()
+	if sc.AccessLog != nil {
+		accessLog = middlewares.NewAccessLogWithOptions(*sc.AccessLog)
+	}
+
+	recovery := middlewares.NewRecoveryMiddleware()
+	recovery.Reporter = sc.PanicReporter
+
+	timeout := middlewares.NewTimeoutMiddleware()
+	if sc.RequestTimeout > 0 {
+		timeout.Timeout = sc.RequestTimeout
+	}
+
+	p := NewPipeline(
+		PipelineEntry{MiddlewareRequestId, middlewares.NewRequestIdMiddleware()},
+		PipelineEntry{MiddlewareTrustedProxies, trustedProxiesHandler},
+		PipelineEntry{MiddlewareTracing, tracingHandler},
+		PipelineEntry{MiddlewareMetrics, metricsHandler},
+		PipelineEntry{MiddlewareRecovery, recovery},
+		PipelineEntry{MiddlewareAccessLog, accessLog},
+		PipelineEntry{MiddlewareSecurityHeaders, securityHeaders},
+		PipelineEntry{MiddlewareCachePolicy, middlewares.NewCachePolicyMiddleware()},
+		PipelineEntry{MiddlewareCompression, compression},
+		PipelineEntry{MiddlewareIPFilter, ipFilterHandler},
+		PipelineEntry{MiddlewareRateLimit, rateLimitHandler},
+		PipelineEntry{MiddlewareConcurrency, concurrencyHandler},
+		PipelineEntry{MiddlewareTimeout, timeout},
+		PipelineEntry{MiddlewareRequireHTTP11, middlewares.NewRequireHTTP11Middleware()},
+		PipelineEntry{MiddlewareMaxBodyLength, middlewares.NewRequireMaxBodyLengthMiddleware()},
+		PipelineEntry{MiddlewareCors, corsHandler},
+		PipelineEntry{MiddlewareContentLength, middlewares.NewRequireContentLengthOrTransferEncodingMiddleware()},
+		PipelineEntry{MiddlewareContentType, middlewares.NewRequireContentTypeMiddleware("application/json")},
+		PipelineEntry{MiddlewareDecompression, decompression},
+		PipelineEntry{MiddlewareConditional, conditional},
+	)
+	return p, nil
+}
+
+// createSecurityHeadersMiddleware returns the security headers of sc.SecurityHeaders or,
+// if not set, of the environment. The main and the admin listener send the same headers.
+func createSecurityHeadersMiddleware(sc *ServerConfig) (*middlewares.SecurityHeadersMiddleware, error) {
+	if sc.SecurityHeaders != nil {
+		m, err := middlewares.NewSecurityHeadersMiddlewareWithConfig(*sc.SecurityHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("invalid security headers: %w", err)
+		}
+		return m, nil
+	}
+
+	m, err := middlewares.NewSecurityHeadersMiddleware()
+	if err != nil {
+		return nil, fmt.Errorf("invalid security headers configuration: %w", err)
+	}
+	return m, nil
+}
+
+// Names returns the names of all entries in order.
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.entries))
+	for i, e := range p.entries {
+		names[i] = e.Name
+	}
+	return names
+}
+
+// Get returns the handler of the named entry.
+func (p *Pipeline) Get(name string) (negroni.Handler, bool) {
+	i := p.index(name)
+	if i < 0 {
+		return nil, false
+	}
+	return p.entries[i].Handler, true
+}
+
+// Append adds a middleware at the end of the pipeline.
+func (p *Pipeline) Append(name string, h negroni.Handler) error {
+	return p.insert(len(p.entries), name, h)
+}
+
+// Prepend adds a middleware at the start of the pipeline.
+func (p *Pipeline) Prepend(name string, h negroni.Handler) error {
+	return p.insert(0, name, h)
+}
+
+// InsertBefore adds a middleware before the entry named before.
+func (p *Pipeline) InsertBefore(before string, name string, h negroni.Handler) error {
+	i := p.index(before)
+	if i < 0 {
+		return unknownEntryError(before)
+	}
+	return p.insert(i, name, h)
+}
+
+// InsertAfter adds a middleware after the entry named after.
+func (p *Pipeline) InsertAfter(after string, name string, h negroni.Handler) error {
+	i := p.index(after)
+	if i < 0 {
+		return unknownEntryError(after)
+	}
+	return p.insert(i+1, name, h)
+}
+
+// Replace replaces the handler of the named entry. A nil handler disables the entry
+// but keeps its position.
+func (p *Pipeline) Replace(name string, h negroni.Handler) error {
+	i := p.index(name)
+	if i < 0 {
+		return unknownEntryError(name)
+	}
+	p.entries[i].Handler = h
+	return nil
+}
+
+// Remove removes the named entry.
+func (p *Pipeline) Remove(name string) error {
+	i := p.index(name)
+	if i < 0 {
+		return unknownEntryError(name)
+	}
+	p.entries = slices.Delete(p.entries, i, i+1)
+	return nil
+}
+
+// MoveBefore moves the named entry before the entry named before.
+func (p *Pipeline) MoveBefore(name string, before string) error {
+	return p.move(name, before, 0)
+}
+
+// MoveAfter moves the named entry after the entry named after.
+func (p *Pipeline) MoveAfter(name string, after string) error {
+	return p.move(name, after, 1)
+}
+
+// Negroni returns a negroni instance running the entries in order.
+func (p *Pipeline) Negroni() *negroni.Negroni {
+	n := negroni.New()
+	for _, e := range p.entries {
+		if e.Handler != nil {
+			n.Use(e.Handler)
+		}
+	}
+	return n
+}
+
+func (p *Pipeline) move(name string, target string, offset int) error {
+	i := p.index(name)
+	if i < 0 {
+		return unknownEntryError(name)
+	}
+	if p.index(target) < 0 {
+		return unknownEntryError(target)
+	}
+	if name == target {
+		return nil
+	}
+
+	entry := p.entries[i]
+	p.entries = slices.Delete(p.entries, i, i+1)
+	p.entries = slices.Insert(p.entries, p.index(target)+offset, entry)
+	return nil
+}
+
+func (p *Pipeline) insert(i int, name string, h negroni.Handler) error {
+	if p.index(name) >= 0 {
+		return fmt.Errorf("middleware %s already exists", name)
+	}
+	p.entries = slices.Insert(p.entries, i, PipelineEntry{Name: name, Handler: h})
+	return nil
+}
+
+func (p *Pipeline) index(name string) int {
+	return slices.IndexFunc(p.entries, func(e PipelineEntry) bool {
+		return e.Name == name
+	})
+}
+
+func unknownEntryError(name string) error {
+	return fmt.Errorf("middleware %s does not exist", name)
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stfsy/go-api-kit/server/metrics"
+	"github.com/stfsy/go-api-kit/server/middlewares"
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func recordingMiddleware(calls *[]string, name string) negroni.Handler {
+	return negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		*calls = append(*calls, name)
+		next(rw, r)
+	})
+}
+
+func defaultPipeline(t *testing.T, sc *ServerConfig) *Pipeline {
+	p, err := DefaultPipeline(sc)
+	a.NoError(t, err)
+	return p
+}
+
+func TestDefaultPipeline_KeepsDefaultOrder(t *testing.T) {
+	a.Equal(t, []string{
+		MiddlewareRequestId,
+		MiddlewareTrustedProxies,
+		MiddlewareTracing,
+		MiddlewareMetrics,
+		MiddlewareRecovery,
+		MiddlewareAccessLog,
+		MiddlewareSecurityHeaders,
+		MiddlewareCachePolicy,
+		MiddlewareCompression,
+		MiddlewareIPFilter,
+		MiddlewareRateLimit,
+		MiddlewareConcurrency,
+		MiddlewareTimeout,
+		MiddlewareRequireHTTP11,
+		MiddlewareMaxBodyLength,
+		MiddlewareCors,
+		MiddlewareContentLength,
+		MiddlewareContentType,
+		MiddlewareDecompression,
+		MiddlewareConditional,
+	}, defaultPipeline(t, &ServerConfig{}).Names())
+}
+
+func TestDefaultPipeline_CorsHasNoHandlerWithoutConfig(t *testing.T) {
+	h, ok := defaultPipeline(t, &ServerConfig{}).Get(MiddlewareCors)
+
+	a.True(t, ok)
+	a.Nil(t, h)
+}
+
+func TestPipeline_InsertMoveRemove(t *testing.T) {
+	assert := a.New(t)
+
+	var calls []string
+	p := NewPipeline(
+		PipelineEntry{"a", recordingMiddleware(&calls, "a")},
+		PipelineEntry{"b", recordingMiddleware(&calls, "b")},
+		PipelineEntry{"c", recordingMiddleware(&calls, "c")},
+	)
+
+	assert.NoError(p.InsertBefore("c", "auth", recordingMiddleware(&calls, "auth")))
+	assert.NoError(p.MoveBefore("c", "a"))
+	assert.NoError(p.MoveAfter("a", "auth"))
+	assert.NoError(p.Remove("b"))
+	assert.NoError(p.Prepend("first", recordingMiddleware(&calls, "first")))
+	assert.NoError(p.Append("last", nil))
+	assert.Equal([]string{"first", "c", "auth", "a", "last"}, p.Names())
+
+	n := p.Negroni()
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal([]string{"first", "c", "auth", "a"}, calls)
+}
+
+func TestDefaultPipeline_KeepsNoCacheName(t *testing.T) {
+	p := defaultPipeline(t, &ServerConfig{})
+
+	_, ok := p.Get("no-cache")
+	a.True(t, ok)
+	a.NoError(t, p.Remove("no-cache"))
+}
+
+func TestPipeline_ReplaceDisablesEntry(t *testing.T) {
+	assert := a.New(t)
+
+	var calls []string
+	p := NewPipeline(PipelineEntry{"a", recordingMiddleware(&calls, "a")})
+
+	assert.NoError(p.Replace("a", nil))
+	assert.Equal([]string{"a"}, p.Names())
+
+	n := p.Negroni()
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(calls)
+}
+
+func TestPipeline_ReturnsErrorsForUnknownAndDuplicateNames(t *testing.T) {
+	assert := a.New(t)
+
+	p := NewPipeline(PipelineEntry{"a", nil})
+
+	assert.EqualError(p.Remove("x"), "middleware x does not exist")
+	assert.EqualError(p.MoveBefore("a", "x"), "middleware x does not exist")
+	assert.EqualError(p.InsertAfter("x", "b", nil), "middleware x does not exist")
+	assert.EqualError(p.Append("a", nil), "middleware a already exists")
+}
+
+func TestPipelineCallback_RemovesCachePolicy(t *testing.T) {
+	assert := a.New(t)
+
+	srv, addr := startServer(t, &ServerConfig{
+		PipelineCallback: func(p *Pipeline) error {
+			return p.Remove(MiddlewareCachePolicy)
+		},
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("GET /test", func(w http.ResponseWriter, r *http.Request) {})
+		},
+	})
+	defer func() { _ = srv.Stop() }()
+
+	resp, err := http.Get("http://" + addr + "/test")
+	assert.NoError(err)
+	_ = resp.Body.Close()
+
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Empty(resp.Header.Get("Cache-Control"))
+	assert.Equal("DENY", resp.Header.Get("X-Frame-Options"))
+}
+
+func TestPipelineCallback_ErrorAbortsStart(t *testing.T) {
+	srv := NewServer(&ServerConfig{
+		PortOverride: "0",
+		PipelineCallback: func(p *Pipeline) error {
+			return errors.New("boom")
+		},
+	})
+
+	a.EqualError(t, srv.Start(), "unable to configure middleware pipeline: boom")
+}
+
+func TestDefaultPipeline_ConcurrencyLimitOnlyIfConfigured(t *testing.T) {
+	h, _ := defaultPipeline(t, &ServerConfig{}).Get(MiddlewareConcurrency)
+	a.Nil(t, h)
+
+	h, _ = defaultPipeline(t, &ServerConfig{ConcurrencyLimit: middlewares.StaticLimit(10)}).Get(MiddlewareConcurrency)
+	a.NotNil(t, h)
+}
+
+func TestDefaultPipeline_ReturnsErrorForInvalidTrustedProxies(t *testing.T) {
+	_, err := DefaultPipeline(&ServerConfig{TrustedProxies: []string{"10.0.0.0/33"}})
+
+	a.ErrorContains(t, err, "invalid trusted proxies")
+}
+
+func TestDefaultPipeline_ReturnsErrorForInvalidSecurityHeaders(t *testing.T) {
+	c := security.DefaultPreset()
+	c.FrameOptions = "ALLOW-FROM https://example.com"
+
+	_, err := DefaultPipeline(&ServerConfig{SecurityHeaders: &c})
+
+	a.ErrorContains(t, err, "invalid security headers")
+}
+
+func TestDefaultPipeline_ReturnsErrorIfRequestMetricsConflict(t *testing.T) {
+	registry := metrics.NewRegistry()
+	_, err := registry.Gauge("http_server_requests_total", "Conflicts with the request counter.")
+	a.NoError(t, err)
+
+	_, err = DefaultPipeline(&ServerConfig{Metrics: registry})
+
+	a.ErrorContains(t, err, "unable to register request metrics")
+}
+
+func TestStart_FailsIfRuntimeMetricsConflict(t *testing.T) {
+	registry := metrics.NewRegistry()
+	_, err := registry.Counter("go_goroutines", "Conflicts with the runtime gauge.")
+	a.NoError(t, err)
+
+	srv := NewServer(&ServerConfig{PortOverride: "0", Metrics: registry})
+
+	a.ErrorContains(t, srv.Start(), "unable to register runtime metrics")
+}
+
+func TestStart_FailsForInvalidIPFilter(t *testing.T) {
+	srv := NewServer(&ServerConfig{PortOverride: "0", IPDenyList: []string{"192.0.2.0/33"}})
+
+	a.ErrorContains(t, srv.Start(), "invalid IP filter")
+}
@@ -0,0 +1,313 @@
+// Package reports receives reports of the browser Reporting API and Content-Security-Policy
+// violation reports, validates and rate limits them and logs them as structured entries.
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stfsy/go-api-kit/server/clientip"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/middlewares/ratelimit"
+	"github.com/stfsy/go-api-kit/server/requestid"
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+var logger = utils.NewLogger("reports")
+
+const (
+	// ContentTypeReports is sent by browsers for endpoints of the Reporting-Endpoints header.
+	ContentTypeReports = "application/reports+json"
+	// ContentTypeCSPReport is sent by browsers for the deprecated report-uri directive.
+	ContentTypeCSPReport = "application/csp-report"
+
+	// TypeCSPViolation is the type of Content-Security-Policy violation reports.
+	TypeCSPViolation = "csp-violation"
+
+	maxTypeLength = 64
+	maxURLLength  = 4096
+	// maxLogValueLength limits each logged value, reports are sent by any client.
+	maxLogValueLength = 256
+)
+
+// loggedBodyFields are the fields of report bodies that are logged, mapped to their log
+// keys. Other fields are only passed to Options.OnReport.
+var loggedBodyFields = []struct {
+	field string
+	key   string
+}{
+	{"effectiveDirective", "effective_directive"},
+	{"blockedURL", "blocked_url"},
+	{"disposition", "disposition"},
+	{"sourceFile", "source_file"},
+	{"lineNumber", "line_number"},
+	{"columnNumber", "column_number"},
+	{"statusCode", "status_code"},
+	{"id", "id"},
+	{"message", "message"},
+	{"reason", "reason"},
+}
+
+// ContentTypes are the request content types accepted by Handler. Pass them to
+// route.ContentTypes, otherwise the content type middleware rejects the reports.
+var ContentTypes = []string{ContentTypeReports, ContentTypeCSPReport, handlers.ContentTypeJson}
+
+// Report is a report in the format of the Reporting API. CSP violation reports sent to a
+// report-uri are converted to this format.
+type Report struct {
+	Type      string         `json:"type"`
+	Age       int64          `json:"age"`
+	URL       string         `json:"url"`
+	UserAgent string         `json:"user_agent"`
+	Body      map[string]any `json:"body"`
+}
+
+// Options configure a Handler.
+type Options struct {
+	// MaxBodySize is the maximum size of a request in bytes. Defaults to 64 KB.
+	MaxBodySize int64
+	// MaxReports is the maximum number of reports per request. Defaults to 100.
+	MaxReports int
+	// RateLimit limits the requests per client. Defaults to 60 requests per minute per IP address.
+	RateLimit ratelimit.Policy
+	// Store counts the requests for RateLimit. Defaults to a MemoryStore.
+	Store ratelimit.Store
+	// OnReport is called for each valid report after it was logged, e.g. to count
+	// violations in a metric.
+	OnReport func(r *http.Request, report Report)
+}
+
+// Handler receives reports sent by browsers to the endpoints of the Reporting-Endpoints
+// header, see security.Config, and to the report-uri of a Content-Security-Policy. Valid
+// reports are logged at Warn and the handler responds with 204 No Content. Reports with
+// an invalid type, age or URL are dropped.
+type Handler struct {
+	maxBodySize int64
+	maxReports  int
+	policy      ratelimit.Policy
+	store       ratelimit.Store
+	onReport    func(r *http.Request, report Report)
+	logger      *slog.Logger
+}
+
+// NewHandler returns a handler with the default options.
+func NewHandler() *Handler {
+	return NewHandlerWithOptions(Options{})
+}
+
+// NewHandlerWithOptions returns a handler configured by options. Zero values use the defaults.
+func NewHandlerWithOptions(options Options) *Handler {
+	h := &Handler{
+		maxBodySize: options.MaxBodySize,
+		maxReports:  options.MaxReports,
+		policy:      options.RateLimit,
+		store:       options.Store,
+		onReport:    options.OnReport,
+		logger:      logger,
+	}
+	if h.maxBodySize <= 0 {
+		h.maxBodySize = 64 * 1024
+	}
+	if h.maxReports <= 0 {
+		h.maxReports = 100
+	}
+	if h.policy.Limit == 0 {
+		h.policy = ratelimit.Policy{Limit: 60, Window: time.Minute}
+	}
+	if h.policy.Name == "" {
+		h.policy.Name = "reports"
+	}
+	if h.policy.Key == nil {
+		h.policy.Key = ratelimit.KeyByIP
+	}
+	if h.store == nil {
+		h.store = ratelimit.NewMemoryStore()
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		handlers.SendMethodNotAllowed(rw, nil)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(handlers.HeaderContentType))
+	if err != nil || (mediaType != ContentTypeReports && mediaType != ContentTypeCSPReport && mediaType != handlers.ContentTypeJson) {
+		handlers.SendUnsupportedMediaType(rw, nil)
+		return
+	}
+
+	if !h.allow(rw, r) {
+		return
+	}
+
+	var reports []Report
+	body := http.MaxBytesReader(rw, r.Body, h.maxBodySize)
+	if mediaType == ContentTypeReports {
+		err = json.NewDecoder(body).Decode(&reports)
+	} else {
+		reports, err = decodeCSPReport(body, r.UserAgent())
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			handlers.SendPayloadTooLarge(rw, nil)
+			return
+		}
+		handlers.SendBadRequest(rw, handlers.CreateErrorDetails("body", "must be a valid report"))
+		return
+	}
+	if len(reports) > h.maxReports {
+		handlers.SendPayloadTooLarge(rw, handlers.CreateErrorDetails("body", fmt.Sprintf("must not contain more than %d reports", h.maxReports)))
+		return
+	}
+
+	for _, report := range reports {
+		if !validReport(report) {
+			continue
+		}
+		h.log(r, report)
+		if h.onReport != nil {
+			h.onReport(r, report)
+		}
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// allow counts the request and responds with 429 Too Many Requests if the client exceeds
+// the limit. If the store fails, requests are allowed.
+func (h *Handler) allow(rw http.ResponseWriter, r *http.Request) bool {
+	if h.policy.Limit < 0 {
+		return true
+	}
+	result, err := h.store.Take(r.Context(), h.policy.Name+":"+h.policy.Key(r), h.policy, time.Now())
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Unable to count report for rate limit policy %s: %s", h.policy.Name, err.Error()))
+		return true
+	}
+	if !result.Allowed {
+		rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+		handlers.SendTooManyRequests(rw, nil)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) log(r *http.Request, report Report) {
+	attrs := []slog.Attr{
+		slog.String("type", report.Type),
+		slog.String("url", truncate(report.URL)),
+		slog.Int64("age", report.Age),
+		slog.String("user_agent", truncate(report.UserAgent)),
+		slog.String("remote_ip", clientip.IP(r)),
+	}
+	if id := requestid.FromContext(r.Context()); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	for _, f := range loggedBodyFields {
+		switch v := report.Body[f.field].(type) {
+		case string:
+			attrs = append(attrs, slog.String(f.key, truncate(v)))
+		case float64:
+			attrs = append(attrs, slog.Float64(f.key, v))
+		case int:
+			attrs = append(attrs, slog.Int(f.key, v))
+		case bool:
+			attrs = append(attrs, slog.Bool(f.key, v))
+		}
+	}
+	h.logger.LogAttrs(r.Context(), slog.LevelWarn, "browser report", attrs...)
+}
+
+// truncate shortens s to maxLogValueLength bytes without splitting a UTF-8 sequence.
+func truncate(s string) string {
+	if len(s) <= maxLogValueLength {
+		return s
+	}
+	end := maxLogValueLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "..."
+}
+
+func validReport(report Report) bool {
+	if report.Type == "" || len(report.Type) > maxTypeLength || report.Age < 0 {
+		return false
+	}
+	for _, c := range report.Type {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	if len(report.URL) > maxURLLength {
+		return false
+	}
+	u, err := url.Parse(report.URL)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// cspReport is the body of reports sent to the report-uri of a Content-Security-Policy.
+type cspReport struct {
+	Report *struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		StatusCode         int    `json:"status-code"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		ScriptSample       string `json:"script-sample"`
+	} `json:"csp-report"`
+}
+
+// decodeCSPReport converts a report-uri report to the format of the Reporting API.
+func decodeCSPReport(body io.Reader, userAgent string) ([]Report, error) {
+	var cr cspReport
+	err := json.NewDecoder(body).Decode(&cr)
+	if err != nil {
+		return nil, err
+	}
+	if cr.Report == nil {
+		return nil, errors.New("csp-report is missing")
+	}
+
+	effectiveDirective := cr.Report.EffectiveDirective
+	if effectiveDirective == "" {
+		effectiveDirective = cr.Report.ViolatedDirective
+	}
+	return []Report{{
+		Type:      TypeCSPViolation,
+		URL:       cr.Report.DocumentURI,
+		UserAgent: userAgent,
+		Body: map[string]any{
+			"documentURL":        cr.Report.DocumentURI,
+			"referrer":           cr.Report.Referrer,
+			"blockedURL":         cr.Report.BlockedURI,
+			"effectiveDirective": effectiveDirective,
+			"originalPolicy":     cr.Report.OriginalPolicy,
+			"disposition":        cr.Report.Disposition,
+			"statusCode":         cr.Report.StatusCode,
+			"sourceFile":         cr.Report.SourceFile,
+			"lineNumber":         cr.Report.LineNumber,
+			"columnNumber":       cr.Report.ColumnNumber,
+			"sample":             cr.Report.ScriptSample,
+		},
+	}}, nil
+}
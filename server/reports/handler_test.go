@@ -0,0 +1,141 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/middlewares/ratelimit"
+	a "github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(options Options) (*Handler, *bytes.Buffer) {
+	var buf bytes.Buffer
+	h := NewHandlerWithOptions(options)
+	h.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	return h, &buf
+}
+
+func postReport(h *Handler, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/reports", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "test-agent")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func logEntries(buf *bytes.Buffer) []map[string]any {
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		_ = json.Unmarshal([]byte(line), &entry)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestHandler_LogsReportingAPIReports(t *testing.T) {
+	assert := a.New(t)
+
+	var received []Report
+	h, buf := newTestHandler(Options{OnReport: func(r *http.Request, report Report) {
+		received = append(received, report)
+	}})
+	rec := postReport(h, ContentTypeReports, `[
+		{"type":"csp-violation","age":10,"url":"https://example.com/admin","user_agent":"Mozilla","body":{"effectiveDirective":"script-src-elem","blockedURL":"https://evil.example","disposition":"enforce"}},
+		{"type":"deprecation","age":0,"url":"https://example.com/","body":{"id":"x"}},
+		{"type":"Invalid Type","url":"https://example.com/"},
+		{"type":"deprecation","url":"javascript:alert(1)"}
+	]`)
+
+	assert.Equal(http.StatusNoContent, rec.Code)
+	assert.Len(received, 2)
+
+	entries := logEntries(buf)
+	assert.Len(entries, 2)
+	assert.Equal("WARN", entries[0]["level"])
+	assert.Equal("csp-violation", entries[0]["type"])
+	assert.Equal("script-src-elem", entries[0]["effective_directive"])
+	assert.Equal("https://evil.example", entries[0]["blocked_url"])
+	assert.Equal("deprecation", entries[1]["type"])
+	assert.Nil(entries[1]["effective_directive"])
+}
+
+func TestHandler_LogsOnlyKnownBodyFieldsTruncated(t *testing.T) {
+	assert := a.New(t)
+
+	h, buf := newTestHandler(Options{})
+	long := strings.Repeat("ä", 200)
+	rec := postReport(h, ContentTypeReports, `[
+		{"type":"csp-violation","url":"https://example.com/","body":{"blockedURL":"`+long+`","lineNumber":3,"secret":"attacker controlled","nested":{"a":1}}}
+	]`)
+	assert.Equal(http.StatusNoContent, rec.Code)
+
+	entries := logEntries(buf)
+	assert.Len(entries, 1)
+	assert.Nil(entries[0]["body"])
+	assert.Nil(entries[0]["secret"])
+	assert.Nil(entries[0]["nested"])
+	assert.Equal(float64(3), entries[0]["line_number"])
+
+	blocked := entries[0]["blocked_url"].(string)
+	assert.LessOrEqual(len(blocked), maxLogValueLength+len("..."))
+	assert.True(strings.HasSuffix(blocked, "ä..."))
+}
+
+func TestHandler_ConvertsCSPReports(t *testing.T) {
+	assert := a.New(t)
+
+	var received []Report
+	h, _ := newTestHandler(Options{OnReport: func(r *http.Request, report Report) {
+		received = append(received, report)
+	}})
+	rec := postReport(h, ContentTypeCSPReport, `{"csp-report":{"document-uri":"https://example.com/admin","violated-directive":"script-src","blocked-uri":"inline","line-number":12}}`)
+
+	assert.Equal(http.StatusNoContent, rec.Code)
+	if assert.Len(received, 1) {
+		assert.Equal(TypeCSPViolation, received[0].Type)
+		assert.Equal("https://example.com/admin", received[0].URL)
+		assert.Equal("test-agent", received[0].UserAgent)
+		assert.Equal("script-src", received[0].Body["effectiveDirective"])
+		assert.Equal(12, received[0].Body["lineNumber"])
+	}
+}
+
+func TestHandler_RejectsInvalidRequests(t *testing.T) {
+	assert := a.New(t)
+
+	h, _ := newTestHandler(Options{MaxBodySize: 256, MaxReports: 1})
+
+	assert.Equal(http.StatusUnsupportedMediaType, postReport(h, "text/plain", "x").Code)
+	assert.Equal(http.StatusBadRequest, postReport(h, ContentTypeReports, "{").Code)
+	assert.Equal(http.StatusBadRequest, postReport(h, ContentTypeCSPReport, "{}").Code)
+	assert.Equal(http.StatusRequestEntityTooLarge, postReport(h, ContentTypeReports, `[{"type":"`+strings.Repeat("x", 300)+`"}]`).Code)
+	assert.Equal(http.StatusRequestEntityTooLarge, postReport(h, ContentTypeReports, `[{},{}]`).Code)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports", nil))
+	assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal("POST", rec.Header().Get("Allow"))
+}
+
+func TestHandler_RateLimitsClients(t *testing.T) {
+	assert := a.New(t)
+
+	h, _ := newTestHandler(Options{RateLimit: ratelimit.Policy{Limit: 2, Window: time.Minute}})
+	assert.Equal(http.StatusNoContent, postReport(h, ContentTypeReports, "[]").Code)
+	assert.Equal(http.StatusNoContent, postReport(h, ContentTypeReports, "[]").Code)
+
+	rec := postReport(h, ContentTypeReports, "[]")
+	assert.Equal(http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(rec.Header().Get("Retry-After"))
+}
@@ -0,0 +1,25 @@
+package requestid
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestNew_ReturnsUUIDv4(t *testing.T) {
+	assert := a.New(t)
+
+	id := New()
+
+	assert.Regexp(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), id)
+	assert.NotEqual(id, New())
+}
+
+func TestFromContext(t *testing.T) {
+	assert := a.New(t)
+
+	assert.Empty(FromContext(context.Background()))
+	assert.Equal("abc", FromContext(NewContext(context.Background(), "abc")))
+}
@@ -0,0 +1,156 @@
+// Package route holds the metadata of the route matched for a request, so the
+// global middlewares can apply per-route overrides before the handler runs.
+package route
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/cache"
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+)
+
+// Options are per-route overrides of the server-wide defaults. Zero values keep the default.
+type Options struct {
+	// MaxBodySize overrides API_KIT_MAX_BODY_SIZE for the route.
+	MaxBodySize int64
+	// ContentTypes replaces the allowed request content types of write requests.
+	ContentTypes []string
+	// CachePolicy replaces the default no-store cache policy.
+	CachePolicy *cache.Policy
+	// Timeout overrides API_KIT_REQUEST_TIMEOUT for the route.
+	Timeout time.Duration
+	// Priority decides which requests are shed first under overload.
+	Priority PriorityClass
+	// RateLimitPolicy is the name of the rate limit policy of the route.
+	RateLimitPolicy string
+	// SecurityHeaders replaces the security headers of the server.
+	SecurityHeaders *security.Config
+	// RequirePreconditions rejects write requests without If-Match or If-Unmodified-Since.
+	RequirePreconditions bool
+}
+
+// PriorityClass orders requests when the server sheds load. Requests with a lower
+// class are shed first. The zero value is PriorityNormal.
+type PriorityClass int
+
+const (
+	PriorityLow      PriorityClass = -1
+	PriorityNormal   PriorityClass = 0
+	PriorityHigh     PriorityClass = 1
+	PriorityCritical PriorityClass = 2
+)
+
+// Option changes route options.
+type Option func(*Options)
+
+// MaxBodySize limits the request body of the route to n bytes.
+func MaxBodySize(n int64) Option {
+	return func(o *Options) {
+		o.MaxBodySize = n
+	}
+}
+
+// ContentTypes allows write requests with one of the given media types, e.g. multipart/form-data.
+func ContentTypes(contentTypes ...string) Option {
+	return func(o *Options) {
+		o.ContentTypes = contentTypes
+	}
+}
+
+// Cache applies policy to responses of the route instead of the default no-store policy.
+// It panics if the policy is invalid, like ServeMux panics on conflicting patterns.
+func Cache(policy cache.Policy) Option {
+	err := policy.Validate()
+	if err != nil {
+		panic(fmt.Sprintf("route: invalid cache policy: %s", err.Error()))
+	}
+	return func(o *Options) {
+		o.CachePolicy = &policy
+	}
+}
+
+// Timeout cancels the request context of the route after d and responds with 503
+// Service Unavailable if the handler has not responded by then.
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// Priority sets the priority class of the route, e.g. PriorityCritical for health checks
+// and important write endpoints.
+func Priority(p PriorityClass) Option {
+	return func(o *Options) {
+		o.Priority = p
+	}
+}
+
+// RateLimitPolicy limits requests to the route with the named policy of the rate limiter.
+func RateLimitPolicy(name string) Option {
+	return func(o *Options) {
+		o.RateLimitPolicy = name
+	}
+}
+
+// SecurityHeaders sends the headers of c instead of the security headers of the server,
+// e.g. a Content-Security-Policy for a route that serves HTML. It panics if c is invalid.
+func SecurityHeaders(c security.Config) Option {
+	err := c.Validate()
+	if err != nil {
+		panic(fmt.Sprintf("route: invalid security headers: %s", err.Error()))
+	}
+	return func(o *Options) {
+		o.SecurityHeaders = &c
+	}
+}
+
+// RequirePreconditions answers write requests to the route with 428 Precondition Required
+// unless they send If-Match or If-Unmodified-Since, so clients cannot overwrite changes
+// they have not seen.
+func RequirePreconditions() Option {
+	return func(o *Options) {
+		o.RequirePreconditions = true
+	}
+}
+
+// Apply returns a copy of o with opts applied.
+func (o Options) Apply(opts ...Option) Options {
+	if len(o.ContentTypes) > 0 {
+		o.ContentTypes = append([]string(nil), o.ContentTypes...)
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Route describes the registered route that matches a request.
+type Route struct {
+	// Pattern is the ServeMux pattern or empty if no pattern matches.
+	Pattern string
+	Options Options
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries rt.
+func NewContext(ctx context.Context, rt *Route) context.Context {
+	return context.WithValue(ctx, contextKey{}, rt)
+}
+
+// FromContext returns the route stored in ctx or nil if the route has not been resolved.
+func FromContext(ctx context.Context) *Route {
+	rt, _ := ctx.Value(contextKey{}).(*Route)
+	return rt
+}
+
+// OptionsFromContext returns the options of the route stored in ctx or zero options.
+func OptionsFromContext(ctx context.Context) Options {
+	rt := FromContext(ctx)
+	if rt == nil {
+		return Options{}
+	}
+	return rt.Options
+}
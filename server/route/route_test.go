@@ -0,0 +1,46 @@
+package route
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/cache"
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestApply_DoesNotModifyReceiver(t *testing.T) {
+	assert := a.New(t)
+
+	base := Options{ContentTypes: []string{"application/json"}}
+	applied := base.Apply(MaxBodySize(10), Timeout(time.Second))
+	applied.ContentTypes[0] = "text/csv"
+
+	assert.Equal(int64(10), applied.MaxBodySize)
+	assert.Equal(time.Second, applied.Timeout)
+	assert.Equal(int64(0), base.MaxBodySize)
+	assert.Equal("application/json", base.ContentTypes[0])
+}
+
+func TestFromContext(t *testing.T) {
+	assert := a.New(t)
+
+	assert.Nil(FromContext(context.Background()))
+	assert.Equal(Options{}, OptionsFromContext(context.Background()))
+
+	rt := &Route{Pattern: "GET /items", Options: Options{MaxBodySize: 1024}}
+	ctx := NewContext(context.Background(), rt)
+	assert.Same(rt, FromContext(ctx))
+	assert.Equal(int64(1024), OptionsFromContext(ctx).MaxBodySize)
+}
+
+func TestCache_PanicsOnInvalidPolicy(t *testing.T) {
+	assert := a.New(t)
+
+	assert.PanicsWithValue("route: invalid cache policy: s-maxage conflicts with private", func() {
+		Cache(cache.Private(time.Minute).WithSharedMaxAge(time.Hour))
+	})
+
+	options := Options{}.Apply(Cache(cache.NoCache()))
+	assert.Equal("no-cache", options.CachePolicy.String())
+}
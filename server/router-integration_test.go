@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/cache"
+	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/stfsy/go-api-kit/server/router"
+	a "github.com/stretchr/testify/assert"
+)
+
+func startRouterTestServer(t *testing.T) string {
+	readBody := func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	srv, addr := startServer(t, &ServerConfig{
+		RouterCallback: func(rt *router.Router) {
+			rt.Group("/v1", func(v1 *router.Router) {
+				v1.HandleFunc("POST /upload", readBody,
+					route.MaxBodySize(int64(config.Get().MaxBodySize)*2),
+					route.ContentTypes("text/csv"))
+				v1.HandleFunc("GET /reference", readBody, route.Cache(cache.Public(time.Minute).WithVary("Accept-Language")))
+				v1.HandleFunc("POST /items", readBody)
+			})
+		},
+	})
+	t.Cleanup(func() { _ = srv.Stop() })
+
+	return "http://" + addr
+}
+
+func TestRouter_RouteOptionsOverrideGlobalMiddleware(t *testing.T) {
+	assert := a.New(t)
+	url := startRouterTestServer(t)
+
+	big := bytes.Repeat([]byte("x"), config.Get().MaxBodySize+1)
+	resp, err := http.Post(url+"/v1/upload", "text/csv", bytes.NewReader(big))
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(url+"/v1/items", "application/json", bytes.NewReader(big))
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err = http.Post(url+"/v1/upload", "application/json", strings.NewReader("{}"))
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Get(url + "/v1/reference")
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal("public, max-age=60", resp.Header.Get("Cache-Control"))
+	assert.Contains(resp.Header.Values("Vary"), "Accept-Language")
+	assert.Empty(resp.Header.Get("Pragma"))
+}
+
+func TestRouter_IfMatchAcceptsETagOfCompressedResponse(t *testing.T) {
+	assert := a.New(t)
+	document := map[string]string{"text": strings.Repeat("lorem ipsum ", 200)}
+
+	srv, addr := startServer(t, &ServerConfig{
+		RouterCallback: func(rt *router.Router) {
+			rt.HandleFunc("GET /document", func(w http.ResponseWriter, r *http.Request) {
+				handlers.SendStructAsJson(w, document)
+			})
+			rt.HandleFunc("PUT /document", func(w http.ResponseWriter, r *http.Request) {
+				etag, err := handlers.JsonETag(document, false)
+				assert.NoError(err)
+				if !handlers.CheckPreconditions(w, r, etag, time.Time{}) {
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}, route.RequirePreconditions())
+		},
+	})
+	t.Cleanup(func() { _ = srv.Stop() })
+	url := "http://" + addr + "/document"
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	_ = resp.Body.Close()
+	assert.Equal("gzip", resp.Header.Get("Content-Encoding"))
+	etag := resp.Header.Get("ETag")
+	assert.True(strings.HasPrefix(etag, "W/"))
+
+	put := func(ifMatch string) int {
+		req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(http.StatusNoContent, put(etag))
+	assert.Equal(http.StatusPreconditionFailed, put(`W/"stale"`))
+}
@@ -0,0 +1,127 @@
+// Package router adds route groups, group- and route-level middleware and per-route
+// options on top of http.ServeMux.
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/stfsy/go-api-kit/server/route"
+	"github.com/urfave/negroni/v3"
+)
+
+// Router registers handlers on a ServeMux. Routes of a group share its prefix, middleware
+// and options. Middleware and options only apply to routes registered after they were added.
+// Routes must be registered before the server starts.
+type Router struct {
+	mux         *http.ServeMux
+	prefix      string
+	middlewares []negroni.Handler
+	options     route.Options
+	routes      map[string]route.Options
+}
+
+// New returns a router that registers routes on mux.
+func New(mux *http.ServeMux) *Router {
+	return &Router{
+		mux:    mux,
+		routes: map[string]route.Options{},
+	}
+}
+
+// Mux returns the underlying ServeMux.
+func (r *Router) Mux() *http.ServeMux {
+	return r.mux
+}
+
+// Use adds middleware to the router. It runs after the global middleware stack and
+// only for routes of this router and its groups.
+func (r *Router) Use(middlewares ...negroni.Handler) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// With returns a copy of the router whose routes use the given options.
+func (r *Router) With(opts ...route.Option) *Router {
+	child := r.clone(r.prefix)
+	child.options = child.options.Apply(opts...)
+	return child
+}
+
+// Group calls fn with a router whose routes are prefixed with prefix. The group inherits
+// the middleware and options of r, middleware added to the group does not affect r.
+func (r *Router) Group(prefix string, fn func(*Router)) {
+	fn(r.clone(r.prefix + strings.TrimSuffix(prefix, "/")))
+}
+
+// Handle registers h for pattern, e.g. "GET /items/{id}". The group prefix is inserted
+// before the path of the pattern.
+func (r *Router) Handle(pattern string, h http.Handler, opts ...route.Option) {
+	pattern = r.prefixPattern(pattern)
+	options := r.options.Apply(opts...)
+
+	r.mux.Handle(pattern, chain(r.middlewares, h))
+	r.routes[pattern] = options
+}
+
+// HandleFunc registers fn for pattern. See Handle.
+func (r *Router) HandleFunc(pattern string, fn http.HandlerFunc, opts ...route.Option) {
+	r.Handle(pattern, fn, opts...)
+}
+
+// Resolve returns the route that matches req. Its pattern is empty if no route matches.
+// Routes registered on the mux directly are resolved without options.
+func (r *Router) Resolve(req *http.Request) *route.Route {
+	_, pattern := r.mux.Handler(req)
+	return &route.Route{
+		Pattern: pattern,
+		Options: r.routes[pattern],
+	}
+}
+
+func (r *Router) clone(prefix string) *Router {
+	return &Router{
+		mux:         r.mux,
+		prefix:      prefix,
+		middlewares: append([]negroni.Handler(nil), r.middlewares...),
+		options:     r.options.Apply(),
+		routes:      r.routes,
+	}
+}
+
+// prefixPattern inserts the prefix between the optional method and host and the path.
+func (r *Router) prefixPattern(pattern string) string {
+	if r.prefix == "" {
+		return pattern
+	}
+
+	method := ""
+	rest := pattern
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		method = pattern[:i]
+		rest = strings.TrimLeft(pattern[i:], " \t")
+	}
+
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		panic(fmt.Sprintf("router: pattern %q has no path", pattern))
+	}
+	host, path := rest[:i], rest[i:]
+
+	if method != "" {
+		return method + " " + host + r.prefix + path
+	}
+	return host + r.prefix + path
+}
+
+// chain returns a handler that runs middlewares in order before h.
+func chain(middlewares []negroni.Handler, h http.Handler) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		m := middlewares[i]
+		next := h
+		h = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			m.ServeHTTP(rw, r, next.ServeHTTP)
+		})
+	}
+	return h
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stfsy/go-api-kit/server/cache"
+	"github.com/stfsy/go-api-kit/server/route"
+	a "github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni/v3"
+)
+
+func headerMiddleware(value string) negroni.Handler {
+	return negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		rw.Header().Add("X-Middleware", value)
+		next(rw, r)
+	})
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(r.Pattern))
+}
+
+func serve(rt *Router, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestGroup_PrefixesPatterns(t *testing.T) {
+	assert := a.New(t)
+
+	rt := New(http.NewServeMux())
+	rt.Group("/v1/", func(v1 *Router) {
+		v1.HandleFunc("GET /items/{id}", ok)
+		v1.Group("/admin", func(admin *Router) {
+			admin.HandleFunc("/users", ok)
+		})
+	})
+
+	assert.Equal("GET /v1/items/{id}", serve(rt, http.MethodGet, "/v1/items/1").Body.String())
+	assert.Equal("/v1/admin/users", serve(rt, http.MethodGet, "/v1/admin/users").Body.String())
+	assert.Equal(http.StatusNotFound, serve(rt, http.MethodGet, "/items/1").Code)
+}
+
+func TestPrefixPattern_KeepsHost(t *testing.T) {
+	rt := New(http.NewServeMux())
+	rt.prefix = "/v1"
+
+	a.Equal(t, "GET example.com/v1/items", rt.prefixPattern("GET example.com/items"))
+	a.Panics(t, func() { rt.prefixPattern("GET items") })
+}
+
+func TestUse_RunsMiddlewareInOrderAndOnlyInGroup(t *testing.T) {
+	assert := a.New(t)
+
+	rt := New(http.NewServeMux())
+	rt.Use(headerMiddleware("root"))
+	rt.Group("/group", func(g *Router) {
+		g.Use(headerMiddleware("group"))
+		g.HandleFunc("/a", ok)
+	})
+	rt.HandleFunc("/b", ok)
+
+	assert.Equal([]string{"root", "group"}, serve(rt, http.MethodGet, "/group/a").Header().Values("X-Middleware"))
+	assert.Equal([]string{"root"}, serve(rt, http.MethodGet, "/b").Header().Values("X-Middleware"))
+}
+
+func TestResolve_ReturnsRouteOptions(t *testing.T) {
+	assert := a.New(t)
+
+	rt := New(http.NewServeMux())
+	rt.With(route.Cache(cache.Public(time.Minute))).Group("/public", func(g *Router) {
+		g.HandleFunc("POST /upload", ok, route.MaxBodySize(1024), route.ContentTypes("multipart/form-data"))
+	})
+	rt.Mux().HandleFunc("/raw", ok)
+
+	resolved := rt.Resolve(httptest.NewRequest(http.MethodPost, "/public/upload", nil))
+	assert.Equal("POST /public/upload", resolved.Pattern)
+	assert.Equal(int64(1024), resolved.Options.MaxBodySize)
+	assert.Equal([]string{"multipart/form-data"}, resolved.Options.ContentTypes)
+	assert.Equal("public, max-age=60", resolved.Options.CachePolicy.String())
+
+	resolved = rt.Resolve(httptest.NewRequest(http.MethodGet, "/raw", nil))
+	assert.Equal("/raw", resolved.Pattern)
+	assert.Equal(route.Options{}, resolved.Options)
+
+	resolved = rt.Resolve(httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	assert.Equal("", resolved.Pattern)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var defaultShutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// Run starts the server and blocks until it has been shut down. Binding errors are
+// returned immediately. A shutdown is triggered by one of the configured
+// ShutdownSignals, by cancellation of ctx or by a call to Stop. Run returns the
+// error of the shutdown sequence, see Shutdown.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	signals := s.serverConfig.ShutdownSignals
+	if len(signals) == 0 {
+		signals = defaultShutdownSignals
+	}
+
+	signalCtx, stop := signal.NotifyContext(ctx, signals...)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// serving stopped without a signal, either Stop was called or accepting failed.
+		// Shutdown waits for a shutdown in progress and returns its result.
+		return errors.Join(err, s.Shutdown(context.WithoutCancel(ctx)))
+	case <-signalCtx.Done():
+	}
+
+	logger.Info(fmt.Sprintf("Shutting down: %s", context.Cause(signalCtx).Error()))
+	// the drain delay and shutdown deadline must not be cut short by the cancelled context
+	err = s.Shutdown(context.WithoutCancel(ctx))
+
+	return errors.Join(<-serveErr, err)
+}
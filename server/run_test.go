@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestRun_ReturnsBindErrorSynchronously(t *testing.T) {
+	assert := a.New(t)
+
+	ln, err := net.Listen("tcp", ":0")
+	assert.NoError(err)
+	defer func() { _ = ln.Close() }()
+
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	srv := NewServer(&ServerConfig{PortOverride: port})
+	err = srv.Run(context.Background())
+	assert.ErrorContains(err, "unable to bind")
+}
+
+func TestRun_ReturnsNilConfigError(t *testing.T) {
+	assert := a.New(t)
+
+	srv := NewServer(nil)
+	assert.Error(srv.Run(context.Background()))
+}
+
+func TestRun_ShutsDownOnContextCancellation(t *testing.T) {
+	assert := a.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listening := make(chan struct{})
+	srv := NewServer(&ServerConfig{
+		PortOverride:   "0",
+		ListenCallback: func() { close(listening) },
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(ctx)
+	}()
+
+	<-listening
+	assert.True(srv.IsReady())
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+	assert.False(srv.IsReady())
+}
+
+func TestRun_ReturnsAfterStop(t *testing.T) {
+	assert := a.New(t)
+
+	listening := make(chan struct{})
+	srv := NewServer(&ServerConfig{
+		PortOverride:   "0",
+		ListenCallback: func() { close(listening) },
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(context.Background())
+	}()
+
+	<-listening
+	assert.NoError(srv.Stop())
+
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+}
+
+func TestRun_ShutsDownOnSignal(t *testing.T) {
+	assert := a.New(t)
+
+	listening := make(chan struct{})
+	srv := NewServer(&ServerConfig{
+		PortOverride:    "0",
+		ListenCallback:  func() { close(listening) },
+		ShutdownSignals: []os.Signal{os.Interrupt},
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(context.Background())
+	}()
+
+	<-listening
+	p, err := os.FindProcess(os.Getpid())
+	assert.NoError(err)
+	err = p.Signal(os.Interrupt)
+	if err != nil {
+		// sending signals to the own process is not supported on all platforms
+		_ = srv.Stop()
+		<-done
+		t.Skipf("unable to send signal: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after signal")
+	}
+}
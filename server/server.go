@@ -2,16 +2,26 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/stfsy/go-api-kit/config"
-	"github.com/stfsy/go-api-kit/server/handlers"
+	"github.com/stfsy/go-api-kit/server/health"
+	"github.com/stfsy/go-api-kit/server/metrics"
 	"github.com/stfsy/go-api-kit/server/middlewares"
+	"github.com/stfsy/go-api-kit/server/middlewares/ratelimit"
+	"github.com/stfsy/go-api-kit/server/middlewares/security"
+	"github.com/stfsy/go-api-kit/server/router"
+	"github.com/stfsy/go-api-kit/server/tracing"
 	"github.com/stfsy/go-api-kit/utils"
 	cors "github.com/stfsy/go-cors"
 	"github.com/urfave/negroni/v3"
@@ -26,70 +36,315 @@ type ServerConfig struct {
 	CrossOriginProtection *http.CrossOriginProtection
 	// MuxCallback registers endpoints and custom middlewares to the HTTP mux.
 	MuxCallback func(*http.ServeMux)
+	// RouterCallback registers endpoints with route groups, per-route middleware and options.
+	// The router registers its routes on the same mux as MuxCallback.
+	RouterCallback func(*router.Router)
+	// PipelineCallback reorders, replaces, disables or inserts middlewares of the default
+	// pipeline. An error aborts the start of the server.
+	PipelineCallback func(*Pipeline) error
 	// MiddlewareCallback customizes the Negroni middleware stack before the server starts.
+	// Middlewares added here run after the pipeline.
 	MiddlewareCallback func(*negroni.Negroni) *negroni.Negroni
 	// ListenCallback is called after the server starts listening, before serving requests.
 	ListenCallback func()
 	// PortOverride manually sets the port. If empty, uses the PORT environment variable.
 	PortOverride string
+	// TLSConfig enables HTTPS. Certificates can be set here or loaded from TLSCertFile and TLSKeyFile.
+	TLSConfig *tls.Config
+	// TLSCertFile and TLSKeyFile enable HTTPS with a key pair that is reloaded from disk when
+	// the files change. If empty, uses the API_KIT_TLS_CERT_FILE and API_KIT_TLS_KEY_FILE environment variables.
+	TLSCertFile string
+	TLSKeyFile  string
+	// HTTPRedirectPort starts an additional plain HTTP listener that redirects all requests to HTTPS.
+	// Only used if TLS is enabled. If empty, uses the API_KIT_HTTP_REDIRECT_PORT environment variable.
+	HTTPRedirectPort string
+	// RequestTimeout is the deadline for handlers to respond before the server responds with
+	// 503 Service Unavailable. If zero, uses the API_KIT_REQUEST_TIMEOUT environment variable.
+	RequestTimeout time.Duration
+	// TrustedProxies are CIDR ranges of proxies whose Forwarded and X-Forwarded-* headers are
+	// trusted to resolve the client IP address. If empty, uses the API_KIT_TRUSTED_PROXIES environment variable.
+	TrustedProxies []string
+	// TrustedProxiesHeader is the header the trusted proxies set, middlewares.ForwardedHeaderXForwardedFor
+	// or middlewares.ForwardedHeaderForwarded. If empty, uses the API_KIT_TRUSTED_PROXIES_HEADER environment variable.
+	TrustedProxiesHeader string
+	// IPAllowList and IPDenyList are CIDR ranges of clients that may or may not send requests.
+	// If both are empty, uses the API_KIT_IP_ALLOW_LIST and API_KIT_IP_DENY_LIST environment variables.
+	IPAllowList []string
+	IPDenyList  []string
+	// RateLimiter limits the requests per client. If nil, uses a limit per client IP address
+	// set by API_KIT_RATE_LIMIT and API_KIT_RATE_LIMIT_WINDOW.
+	RateLimiter *ratelimit.Middleware
+	// ConcurrencyLimit caps the number of requests in flight, e.g. with middlewares.NewAIMDLimit.
+	// If nil, uses the static limit set by API_KIT_CONCURRENCY_LIMIT.
+	ConcurrencyLimit middlewares.ConcurrencyLimit
+	// AccessLog configures the fields, sampling and skip rules of the access log. If nil,
+	// uses the API_KIT_ACCESS_LOG_* environment variables.
+	AccessLog *middlewares.AccessLogOptions
+	// Compression configures the encoders, minimum size and content types of compressed
+	// responses. If nil, uses gzip and deflate and API_KIT_COMPRESSION_MIN_SIZE.
+	Compression *middlewares.CompressionOptions
+	// Decompression configures the decoders and limits for compressed request bodies. If nil,
+	// uses gzip and deflate and the API_KIT_MAX_DECOMPRESSED_BODY_SIZE and API_KIT_MAX_DECOMPRESSION_RATIO limits.
+	Decompression *middlewares.DecompressionOptions
+	// SecurityHeaders configures the security headers of responses, e.g. security.JSONAPIPreset().
+	// If nil, uses API_KIT_SECURITY_HEADERS_PRESET and the header environment variables.
+	SecurityHeaders *security.Config
+	// ConditionalRequests configures the ETags of JSON responses. If nil, uses strong ETags
+	// for responses up to API_KIT_ETAG_MAX_SIZE bytes.
+	ConditionalRequests *middlewares.ConditionalRequestsOptions
+	// PanicReporter is called for each panic recovered from a handler, e.g. to send it to an
+	// error tracking service.
+	PanicReporter middlewares.PanicReporter
+	// TraceExporter enables tracing and receives the ended spans. If nil, uses the exporter
+	// selected by API_KIT_TRACE_EXPORTER.
+	TraceExporter tracing.Exporter
+	// Metrics is the registry for request and runtime metrics. If nil, the server creates
+	// one. Services register their own metrics in the registry returned by Server.Metrics.
+	Metrics *metrics.Registry
+	// HealthCheckInterval is the time for which the results of health checks are cached.
+	// If zero, uses the API_KIT_HEALTH_CHECK_INTERVAL environment variable.
+	HealthCheckInterval time.Duration
+	// ShutdownDrainDelay is the time between failing readiness and closing the listeners, giving
+	// load balancers time to stop routing traffic. If zero, uses the API_KIT_SHUTDOWN_DRAIN_DELAY environment variable.
+	ShutdownDrainDelay time.Duration
+	// ShutdownTimeout is the deadline for in-flight requests to finish.
+	// If zero, uses the API_KIT_SHUTDOWN_TIMEOUT environment variable.
+	ShutdownTimeout time.Duration
+	// ShutdownHookTimeout is the deadline for shutdown hooks to finish. It starts after in-flight
+	// requests finished or ShutdownTimeout passed. If zero, uses the API_KIT_SHUTDOWN_HOOK_TIMEOUT environment variable.
+	ShutdownHookTimeout time.Duration
+	// ShutdownSignals are the signals that make Run shut the server down. Defaults to SIGINT and SIGTERM.
+	ShutdownSignals []os.Signal
+	// AdminConfig starts an additional listener for internal endpoints. If nil, the admin
+	// listener is only started if API_KIT_ADMIN_PORT or API_KIT_ADMIN_SOCKET_PATH is set.
+	AdminConfig *AdminConfig
 }
 
 type Server struct {
-	serverConfig  *ServerConfig
-	server        *http.Server
-	serverContext context.Context
+	serverConfig   *ServerConfig
+	server         *http.Server
+	redirectServer *http.Server
+	adminServer    *http.Server
+	serverContext  context.Context
+	health         *health.Registry
+	metrics        *metrics.Registry
+	addr           net.Addr
+	adminAddr      net.Addr
+	redirectAddr   net.Addr
+	ready          atomic.Bool
+	hooksMu        sync.Mutex
+	hooks          []shutdownHook
+	shutdownOnce   sync.Once
+	shutdownErr    error
 }
 
 func NewServer(serverConfig *ServerConfig) *Server {
+	registry := metrics.NewRegistry()
+	if serverConfig != nil && serverConfig.Metrics != nil {
+		registry = serverConfig.Metrics
+	}
+	// Start sets the interval of health checks, reading the configuration here could panic
 	return &Server{
 		serverConfig:  serverConfig,
 		server:        nil,
 		serverContext: context.Background(),
+		health:        health.NewRegistry(0),
+		metrics:       registry,
 	}
 }
 
+// Addr returns the address the server is bound to or nil if it is not listening yet.
+// Useful if the port was set to 0 to bind to an ephemeral port.
+func (s *Server) Addr() net.Addr {
+	return s.addr
+}
+
+// AdminAddr returns the address the admin listener is bound to or nil if there is none.
+func (s *Server) AdminAddr() net.Addr {
+	return s.adminAddr
+}
+
+// RedirectAddr returns the address the HTTP to HTTPS redirect listener is bound to or nil
+// if there is none.
+func (s *Server) RedirectAddr() net.Addr {
+	return s.redirectAddr
+}
+
+// Health returns the registry for dependency checks reported by the health, readiness
+// and startup endpoints.
+func (s *Server) Health() *health.Registry {
+	return s.health
+}
+
+// Metrics returns the registry for request, runtime and custom metrics served by the
+// metrics endpoint of the admin listener.
+func (s *Server) Metrics() *metrics.Registry {
+	return s.metrics
+}
+
+// IsReady returns true while the server is accepting traffic. It turns false as
+// soon as a shutdown begins.
+func (s *Server) IsReady() bool {
+	return s != nil && s.ready.Load()
+}
+
+// ReadinessHandler responds with the readiness report of the health registry. It fails
+// with 503 if a critical check fails and once a shutdown has begun, so load balancers
+// stop routing new requests to the server.
+func (s *Server) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	s.health.ReadyHandler(w, r)
+}
+
+// Start binds the listeners and serves requests until the server is stopped. It blocks,
+// so it is usually called in a goroutine. Prefer Run, which also handles shutdown.
 func (s *Server) Start() error {
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ln)
+}
+
+// listen builds the handler chain and binds all listeners. Once it returns, the
+// server is ready and ListenCallback has been called.
+func (s *Server) listen() (_ net.Listener, err error) {
 	if s == nil || s.serverConfig == nil {
-		return fmt.Errorf("server configuration is nil")
+		return nil, fmt.Errorf("server configuration is nil")
 	}
 
 	mux := http.NewServeMux()
+	rt := router.New(mux)
 	if s.serverConfig.MuxCallback != nil {
 		s.serverConfig.MuxCallback(mux)
 	}
+	if s.serverConfig.RouterCallback != nil {
+		s.serverConfig.RouterCallback(rt)
+	}
 
-	mux.HandleFunc("/", handlers.NotFoundHandler)
+	configuration := config.Get()
 
-	n := createMiddlewareHandler(s.serverContext, s.serverConfig)
+	healthCheckInterval := time.Duration(configuration.HealthCheckInterval) * time.Second
+	if s.serverConfig.HealthCheckInterval > 0 {
+		healthCheckInterval = s.serverConfig.HealthCheckInterval
+	}
+	s.health.SetInterval(healthCheckInterval)
+
+	err = metrics.RegisterRuntimeMetrics(s.metrics)
+	if err != nil {
+		return nil, fmt.Errorf("unable to register runtime metrics: %w", err)
+	}
+
+	// a copy, so the exporter created from the environment and the server's registry
+	// do not leak into the caller's configuration
+	sc := *s.serverConfig
+	sc.Metrics = s.metrics
+	if sc.TraceExporter == nil {
+		exporter, err := createTraceExporter(configuration)
+		if err != nil {
+			return nil, err
+		}
+		sc.TraceExporter = exporter
+	}
+	if closer, ok := sc.TraceExporter.(io.Closer); ok && s.serverConfig.TraceExporter == nil {
+		// the exporter created here is closed on shutdown or, if the server does not
+		// start, right away
+		defer func() {
+			if err != nil {
+				_ = closer.Close()
+				return
+			}
+			s.RegisterShutdownHook("trace-exporter", func(context.Context) error {
+				return closer.Close()
+			})
+		}()
+	}
+
+	pipeline, err := DefaultPipeline(&sc)
+	if err != nil {
+		return nil, err
+	}
+	if s.serverConfig.PipelineCallback != nil {
+		err := s.serverConfig.PipelineCallback(pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure middleware pipeline: %w", err)
+		}
+	}
+
+	n := pipeline.Negroni()
 	if s.serverConfig.MiddlewareCallback != nil {
 		n = s.serverConfig.MiddlewareCallback(n)
 	}
-	n.UseHandler(mux)
+	n.UseHandler(createMuxHandler(mux))
 
 	csrfProtection := s.serverConfig.CrossOriginProtection
 	if csrfProtection == nil {
 		csrfProtection = createCrossOritinProtection()
 	}
 
-	configuration := config.Get()
 	port := configuration.Port
 	if s.serverConfig.PortOverride != "" {
 		port = s.serverConfig.PortOverride
 	}
 
-	s.server = createServer(port, csrfProtection.Handler(n))
+	tlsConfig, err := createTLSConfig(s.serverConfig, configuration)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure TLS: %w", err)
+	}
+
+	s.server = createServer(port, createRouteResolver(rt, csrfProtection.Handler(n)))
+	s.server.TLSConfig = tlsConfig
 
 	ln, err := net.Listen("tcp", s.server.Addr)
 	if err != nil {
-		return fmt.Errorf("unable to bind to %s: %w", s.server.Addr, err)
+		return nil, fmt.Errorf("unable to bind to %s: %w", s.server.Addr, err)
+	}
+	s.addr = ln.Addr()
+
+	if tlsConfig != nil {
+		// redirect to the bound port, the configured port is 0 for ephemeral ports
+		_, boundPort, _ := net.SplitHostPort(ln.Addr().String())
+		err = s.startRedirectServer(boundPort, configuration)
+		if err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
 	}
 
+	err = s.startAdminServer(configuration)
+	if err != nil {
+		_ = ln.Close()
+		if s.redirectServer != nil {
+			_ = s.redirectServer.Close()
+		}
+		return nil, err
+	}
+
+	s.ready.Store(true)
+	s.health.MarkStarted()
+
 	if s.serverConfig.ListenCallback != nil {
 		s.serverConfig.ListenCallback()
 	}
 
-	logger.Info(fmt.Sprintf("Listening on port %s", port))
-	err = s.server.Serve(ln)
+	if tlsConfig != nil {
+		logger.Info(fmt.Sprintf("Listening on %s (TLS)", s.addr.String()))
+	} else {
+		logger.Info(fmt.Sprintf("Listening on %s", s.addr.String()))
+	}
+
+	return ln, nil
+}
+
+// serve accepts connections on ln until the server is stopped.
+func (s *Server) serve(ln net.Listener) error {
+	var err error
+	if s.server.TLSConfig != nil {
+		// certificates are provided by the TLS config, so no files are passed here
+		err = s.server.ServeTLS(ln, "", "")
+	} else {
+		err = s.server.Serve(ln)
+	}
 
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("unable to accept incoming connections: %w", err)
@@ -98,6 +353,92 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// startRedirectServer binds the optional plain HTTP listener that redirects to HTTPS
+// and serves it in the background. It is a no-op if no redirect port is configured.
+func (s *Server) startRedirectServer(httpsPort string, configuration config.Configuration) error {
+	redirectPort := configuration.HttpRedirectPort
+	if s.serverConfig.HTTPRedirectPort != "" {
+		redirectPort = s.serverConfig.HTTPRedirectPort
+	}
+	if redirectPort == "" {
+		return nil
+	}
+
+	s.redirectServer = createServer(redirectPort, createHttpsRedirectHandler(httpsPort))
+
+	ln, err := net.Listen("tcp", s.redirectServer.Addr)
+	if err != nil {
+		return fmt.Errorf("unable to bind to %s: %w", s.redirectServer.Addr, err)
+	}
+	s.redirectAddr = ln.Addr()
+
+	logger.Info(fmt.Sprintf("Redirecting HTTP on port %s to HTTPS", redirectPort))
+	go func() {
+		err := s.redirectServer.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(fmt.Sprintf("Unable to accept incoming connections on redirect listener: %s", err.Error()))
+		}
+	}()
+
+	return nil
+}
+
+// createTLSConfig returns the TLS configuration for the server or nil if TLS is not enabled.
+func createTLSConfig(sc *ServerConfig, c config.Configuration) (*tls.Config, error) {
+	certFile := c.TlsCertFile
+	if sc.TLSCertFile != "" {
+		certFile = sc.TLSCertFile
+	}
+	keyFile := c.TlsKeyFile
+	if sc.TLSKeyFile != "" {
+		keyFile = sc.TLSKeyFile
+	}
+
+	if sc.TLSConfig == nil && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	var tlsConfig *tls.Config
+	if sc.TLSConfig != nil {
+		tlsConfig = sc.TLSConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("both certificate and key file are required")
+		}
+
+		reloader, err := newCertificateReloader(certFile, keyFile, time.Duration(c.TlsReloadInterval)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = reloader.GetCertificate
+	}
+
+	if len(tlsConfig.Certificates) == 0 && tlsConfig.GetCertificate == nil && tlsConfig.GetConfigForClient == nil {
+		return nil, fmt.Errorf("no certificate configured")
+	}
+
+	return tlsConfig, nil
+}
+
+// createTraceExporter returns the exporter selected by API_KIT_TRACE_EXPORTER or nil if
+// tracing is disabled. The caller closes a file exporter.
+func createTraceExporter(c config.Configuration) (tracing.Exporter, error) {
+	switch c.TraceExporter {
+	case "":
+		return nil, nil
+	case "stdout":
+		return tracing.NewStdoutExporter(), nil
+	case "file":
+		return tracing.NewFileExporter(c.TraceFile)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %s", c.TraceExporter)
+	}
+}
+
 func createCrossOritinProtection() *http.CrossOriginProtection {
 	return &http.CrossOriginProtection{}
 }
@@ -124,36 +465,3 @@ func createServer(port string, h http.Handler) *http.Server {
 		IdleTimeout:  time.Duration(c.IdleTimeout) * time.Second,
 	}
 }
-
-func createMiddlewareHandler(_ctx context.Context, sc *ServerConfig) *negroni.Negroni {
-	n := negroni.New()
-	n.Use(negroni.NewRecovery())
-	n.Use(middlewares.NewAccessLog())
-	n.Use(middlewares.NewRespondWithSecurityHeadersMiddleware())
-	n.Use(middlewares.NewNoCacheHeadersMiddleware())
-	n.Use(middlewares.NewRequireHTTP11Middleware())
-	n.Use(middlewares.NewRequireMaxBodyLengthMiddleware())
-	if sc.CorsConfig != nil {
-		n.Use(cors.New(*sc.CorsConfig))
-	}
-	n.Use(middlewares.NewRequireContentLengthOrTransferEncodingMiddleware())
-	n.Use(middlewares.NewRequireContentTypeMiddleware("application/json"))
-	return n
-}
-
-func (s *Server) Stop() {
-	// Set a timeout for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 9*time.Second)
-	defer cancel()
-
-	if s == nil || s.server == nil {
-		logger.Info("Server not running")
-		return
-	}
-
-	if err := s.server.Shutdown(ctx); err != nil {
-		logger.Info(fmt.Sprintf("Server shutdown error: %v", err))
-	} else {
-		logger.Info("Server stopped")
-	}
-}
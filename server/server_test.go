@@ -3,21 +3,54 @@ package server
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stfsy/go-api-kit/config"
+	"github.com/stfsy/go-api-kit/server/health"
 	a "github.com/stretchr/testify/assert"
 )
 
-// startTestServer starts the server with a simple /test handler and returns a
-// function to stop it. Tests should call defer stop().
-func startTestServer(_t *testing.T) func() {
-	srv := NewServer(&ServerConfig{
+// startServer starts srv with sc on an ephemeral port unless a port is set and waits
+// until it accepts connections. It returns the server and its address.
+func startServer(t *testing.T, sc *ServerConfig) (*Server, string) {
+	if sc.PortOverride == "" {
+		sc.PortOverride = "0"
+	}
+	listening := make(chan struct{})
+	sc.ListenCallback = func() { close(listening) }
+
+	srv := NewServer(sc)
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- srv.Start()
+	}()
+
+	select {
+	case <-listening:
+	case err := <-startErr:
+		t.Fatalf("unable to start server: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not start")
+	}
+
+	port := srv.Addr().(*net.TCPAddr).Port
+	return srv, net.JoinHostPort("localhost", strconv.Itoa(port))
+}
+
+// startTestServer starts the server with a simple /test handler and returns its
+// address and a function to stop it. Tests should call defer stop().
+func startTestServer(t *testing.T) (string, func()) {
+	srv, addr := startServer(t, &ServerConfig{
 		MuxCallback: func(mux *http.ServeMux) {
 			mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 				_, e := io.ReadAll(r.Body)
@@ -31,26 +64,17 @@ func startTestServer(_t *testing.T) func() {
 		},
 	})
 
-	go func() {
-		// Ignore Start errors in tests; Start logs errors via logger.
-		_ = srv.Start()
-	}()
-
-	// Wait briefly for server to start. In production tests, prefer sync.
-	time.Sleep(300 * time.Millisecond)
-
-	return func() {
+	return addr, func() {
 		// ensure graceful shutdown
-		srv.Stop()
-		time.Sleep(50 * time.Millisecond)
+		_ = srv.Stop()
 	}
 }
 
 func TestGET_ReturnsOK(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
-	resp, err := http.Get("http://localhost:8080/test")
+	resp, err := http.Get("http://" + addr + "/test")
 	a := a.New(t)
 	a.NoError(err)
 	defer func() { _ = resp.Body.Close() }()
@@ -60,10 +84,10 @@ func TestGET_ReturnsOK(t *testing.T) {
 }
 
 func TestSecurityHeaders_AreSet(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
-	resp, err := http.Get("http://localhost:8080/test")
+	resp, err := http.Get("http://" + addr + "/test")
 	a := a.New(t)
 	a.NoError(err)
 	defer func() { _ = resp.Body.Close() }()
@@ -74,10 +98,10 @@ func TestSecurityHeaders_AreSet(t *testing.T) {
 }
 
 func TestPOST_WithoutLengthOrTransferEncoding_Returns411(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
-	req, _ := http.NewRequest("POST", "http://localhost:8080/test", nil)
+	req, _ := http.NewRequest("POST", "http://"+addr+"/test", nil)
 	resp, err := http.DefaultClient.Do(req)
 	a := a.New(t)
 	a.NoError(err)
@@ -86,10 +110,10 @@ func TestPOST_WithoutLengthOrTransferEncoding_Returns411(t *testing.T) {
 }
 
 func TestPOST_WithBodyButNoContentType_Returns415(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
-	req, _ := http.NewRequest("POST", "http://localhost:8080/test", strings.NewReader("x"))
+	req, _ := http.NewRequest("POST", "http://"+addr+"/test", strings.NewReader("x"))
 	// Don't set Content-Type
 	req.Header.Del("Content-Type")
 	resp, err := http.DefaultClient.Do(req)
@@ -100,10 +124,10 @@ func TestPOST_WithBodyButNoContentType_Returns415(t *testing.T) {
 }
 
 func TestCacheHeadersSet(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
-	resp, err := http.Get("http://localhost:8080/test")
+	resp, err := http.Get("http://" + addr + "/test")
 	assert := a.New(t)
 	assert.NoError(err)
 	defer func() { _ = resp.Body.Close() }()
@@ -115,11 +139,11 @@ func TestCacheHeadersSet(t *testing.T) {
 }
 
 func TestRequireHTTP11RejectsHTTP10(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
 	// Open raw TCP connection and send an HTTP/1.0 request line so the server sees ProtoMajor=1 ProtoMinor=0
-	conn, err := net.Dial("tcp", "localhost:8080")
+	conn, err := net.Dial("tcp", addr)
 	assert := a.New(t)
 	assert.NoError(err)
 	defer func() { _ = conn.Close() }()
@@ -142,13 +166,13 @@ func TestRequireHTTP11RejectsHTTP10(t *testing.T) {
 	assert.Equal("400", fields[1])
 }
 func TestMaxBodyLengthEnforced(t *testing.T) {
-	stop := startTestServer(t)
+	addr, stop := startTestServer(t)
 	defer stop()
 
 	cfg := config.Get()
 	// create a body that is one byte larger than allowed
 	big := bytes.Repeat([]byte("x"), cfg.MaxBodySize+1)
-	req, _ := http.NewRequest("POST", "http://localhost:8080/test", bytes.NewReader(big))
+	req, _ := http.NewRequest("POST", "http://"+addr+"/test", bytes.NewReader(big))
 	// set content-type so content-type middleware allows passing
 	req.Header.Set("Content-Type", "application/json")
 
@@ -158,3 +182,121 @@ func TestMaxBodyLengthEnforced(t *testing.T) {
 	assert.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
 	defer func() { _ = resp.Body.Close() }()
 }
+
+func TestTLS_ServesHTTPSAndRedirectsHTTP(t *testing.T) {
+	assert := a.New(t)
+
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), "localhost")
+	srv, _ := startServer(t, &ServerConfig{
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			})
+		},
+		PortOverride:     "8443",
+		TLSCertFile:      certFile,
+		TLSKeyFile:       keyFile,
+		HTTPRedirectPort: "8081",
+	})
+	defer func() { _ = srv.Stop() }()
+
+	certPem, err := os.ReadFile(certFile)
+	assert.NoError(err)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPem)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get("https://localhost:8443/test")
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("ok", string(b))
+
+	redirect, err := client.Get("http://localhost:8081/test?x=1")
+	assert.NoError(err)
+	defer func() { _ = redirect.Body.Close() }()
+	assert.Equal(http.StatusPermanentRedirect, redirect.StatusCode)
+	assert.Equal("https://localhost:8443/test?x=1", redirect.Header.Get("Location"))
+}
+
+func TestTLS_RedirectsToBoundPort(t *testing.T) {
+	assert := a.New(t)
+
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), "localhost")
+	srv, addr := startServer(t, &ServerConfig{
+		TLSCertFile:      certFile,
+		TLSKeyFile:       keyFile,
+		HTTPRedirectPort: "8082",
+	})
+	defer func() { _ = srv.Stop() }()
+	_, port, _ := net.SplitHostPort(addr)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	redirect, err := client.Get("http://localhost:8082/test")
+	assert.NoError(err)
+	defer func() { _ = redirect.Body.Close() }()
+	assert.Equal("https://localhost:"+port+"/test", redirect.Header.Get("Location"))
+	assert.NotEqual("0", port)
+}
+
+func TestCreateTLSConfig_DisabledByDefault(t *testing.T) {
+	assert := a.New(t)
+
+	tlsConfig, err := createTLSConfig(&ServerConfig{}, config.Get())
+	assert.NoError(err)
+	assert.Nil(tlsConfig)
+}
+
+func TestCreateTLSConfig_RequiresCertificateAndKey(t *testing.T) {
+	assert := a.New(t)
+
+	_, err := createTLSConfig(&ServerConfig{TLSCertFile: "cert.pem"}, config.Get())
+	assert.Error(err)
+
+	_, err = createTLSConfig(&ServerConfig{TLSConfig: &tls.Config{}}, config.Get())
+	assert.Error(err)
+}
+
+func TestRequestId_IsEchoedAndIncludedInErrors(t *testing.T) {
+	addr, stop := startTestServer(t)
+	defer stop()
+
+	req, _ := http.NewRequest("GET", "http://"+addr+"/unknown", nil)
+	req.Header.Set("X-Request-Id", "client-id-1")
+	resp, err := http.DefaultClient.Do(req)
+	assert := a.New(t)
+	assert.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	assert.Equal("client-id-1", resp.Header.Get("X-Request-Id"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(string(body), `"request_id":"client-id-1"`)
+}
+
+func TestHealthCheckInterval_IsAppliedOnStart(t *testing.T) {
+	assert := a.New(t)
+
+	srv, _ := startServer(t, &ServerConfig{HealthCheckInterval: time.Minute})
+	t.Cleanup(func() { _ = srv.Stop() })
+
+	calls := 0
+	srv.Health().Register(health.Check{Name: "db", Check: func(ctx context.Context) error {
+		calls++
+		return nil
+	}})
+	srv.Health().Health(context.Background())
+	srv.Health().Health(context.Background())
+
+	assert.Equal(1, calls)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stfsy/go-api-kit/config"
+)
+
+type shutdownHook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// RegisterShutdownHook registers fn to be called during shutdown after in-flight requests
+// have finished. Hooks run in reverse registration order, so resources opened first are
+// released last. The context passed to fn expires after the shutdown hook timeout, which
+// starts when the hooks start, so slow in-flight requests do not use up their time.
+func (s *Server) RegisterShutdownHook(name string, fn func(context.Context) error) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Stop gracefully shuts down the server. See Shutdown.
+func (s *Server) Stop() error {
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown stops the server in the following order:
+//  1. readiness starts failing
+//  2. the configured drain delay elapses, so load balancers can stop routing traffic
+//  3. listeners are closed and in-flight requests are awaited until the shutdown timeout,
+//     the admin listener is closed last
+//  4. shutdown hooks run in reverse registration order until the shutdown hook timeout
+//  5. connections that are still open are closed forcefully
+//
+// Both timeouts are bounded by the deadline of ctx.
+//
+// The returned error reports every step that did not finish. Calling Shutdown more
+// than once returns the result of the first call.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s == nil || s.server == nil {
+		logger.Info("Server not running")
+		return nil
+	}
+
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+
+	return s.shutdownErr
+}
+
+func (s *Server) shutdown(ctx context.Context) error {
+	s.ready.Store(false)
+	s.health.MarkShuttingDown()
+
+	drainDelay, timeout, hookTimeout := s.shutdownDurations()
+	if drainDelay > 0 {
+		logger.Info(fmt.Sprintf("Draining for %s before closing listeners", drainDelay))
+		timer := time.NewTimer(drainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	requestsCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the admin listener is closed last, so health checks keep answering while
+	// in-flight requests finish
+	servers := []struct {
+		name   string
+		server *http.Server
+	}{
+		{"redirect listener", s.redirectServer},
+		{"in-flight requests", s.server},
+		{"admin listener", s.adminServer},
+	}
+
+	var errs []error
+	forceClose := false
+
+	for _, srv := range servers {
+		if srv.server == nil {
+			continue
+		}
+		err := srv.server.Shutdown(requestsCtx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s did not finish: %w", srv.name, err))
+			forceClose = true
+		}
+	}
+
+	s.hooksMu.Lock()
+	hooks := make([]shutdownHook, len(s.hooks))
+	copy(hooks, s.hooks)
+	s.hooksMu.Unlock()
+
+	// hooks get their own budget, the requests may have used up theirs
+	hooksCtx, cancelHooks := context.WithTimeout(ctx, hookTimeout)
+	defer cancelHooks()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		err := hooks[i].fn(hooksCtx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s did not finish: %w", hooks[i].name, err))
+		}
+	}
+
+	if forceClose {
+		for _, srv := range servers {
+			if srv.server != nil {
+				_ = srv.server.Close()
+			}
+		}
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Server shutdown incomplete: %s", err.Error()))
+		return err
+	}
+
+	logger.Info("Server stopped")
+	return nil
+}
+
+func (s *Server) shutdownDurations() (time.Duration, time.Duration, time.Duration) {
+	c := config.Get()
+
+	drainDelay := time.Duration(c.ShutdownDrainDelay) * time.Second
+	if s.serverConfig.ShutdownDrainDelay > 0 {
+		drainDelay = s.serverConfig.ShutdownDrainDelay
+	}
+
+	timeout := time.Duration(c.ShutdownTimeout) * time.Second
+	if s.serverConfig.ShutdownTimeout > 0 {
+		timeout = s.serverConfig.ShutdownTimeout
+	}
+
+	hookTimeout := time.Duration(c.ShutdownHookTimeout) * time.Second
+	if s.serverConfig.ShutdownHookTimeout > 0 {
+		hookTimeout = s.serverConfig.ShutdownHookTimeout
+	}
+
+	return drainDelay, timeout, hookTimeout
+}
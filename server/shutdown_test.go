@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func startShutdownTestServer(t *testing.T, sc *ServerConfig) (*Server, string) {
+	srv, addr := startServer(t, sc)
+	if !srv.IsReady() {
+		t.Fatalf("server did not become ready")
+	}
+	return srv, addr
+}
+
+func TestStop_NotRunning(t *testing.T) {
+	assert := a.New(t)
+
+	srv := NewServer(&ServerConfig{})
+	assert.NoError(srv.Stop())
+}
+
+func TestShutdown_RunsHooksInReverseOrder(t *testing.T) {
+	assert := a.New(t)
+
+	srv, _ := startShutdownTestServer(t, &ServerConfig{})
+
+	var order []string
+	srv.RegisterShutdownHook("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	srv.RegisterShutdownHook("second", func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	assert.NoError(srv.Stop())
+	assert.Equal([]string{"second", "first"}, order)
+	assert.False(srv.IsReady())
+}
+
+func TestShutdown_ReportsFailedHooks(t *testing.T) {
+	assert := a.New(t)
+
+	srv, _ := startShutdownTestServer(t, &ServerConfig{})
+	srv.RegisterShutdownHook("database", func(ctx context.Context) error {
+		return errors.New("connection pool busy")
+	})
+
+	err := srv.Stop()
+	assert.ErrorContains(err, "shutdown hook database did not finish: connection pool busy")
+	// the result of the first shutdown is returned again
+	assert.Equal(err, srv.Stop())
+}
+
+func TestShutdown_ReportsUnfinishedRequests(t *testing.T) {
+	assert := a.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	srv, addr := startShutdownTestServer(t, &ServerConfig{
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			})
+		},
+		ShutdownTimeout: 100 * time.Millisecond,
+	})
+
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	err := srv.Stop()
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.ErrorContains(err, "in-flight requests did not finish")
+}
+
+func TestShutdown_HooksHaveTheirOwnTimeout(t *testing.T) {
+	assert := a.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	srv, addr := startShutdownTestServer(t, &ServerConfig{
+		MuxCallback: func(mux *http.ServeMux) {
+			mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			})
+		},
+		ShutdownTimeout:     100 * time.Millisecond,
+		ShutdownHookTimeout: time.Second,
+	})
+
+	var hookErr error
+	var remaining time.Duration
+	srv.RegisterShutdownHook("database", func(ctx context.Context) error {
+		hookErr = ctx.Err()
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return nil
+	})
+
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	err := srv.Stop()
+	assert.ErrorContains(err, "in-flight requests did not finish")
+	assert.NotContains(err.Error(), "shutdown hook")
+	assert.NoError(hookErr)
+	assert.Greater(remaining, 500*time.Millisecond)
+}
+
+func TestShutdown_FailsReadinessDuringDrain(t *testing.T) {
+	assert := a.New(t)
+
+	srv, addr := startShutdownTestServer(t, &ServerConfig{
+		ShutdownDrainDelay: 300 * time.Millisecond,
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.Stop()
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// listeners stay open during the drain delay while readiness is failing
+	assert.False(srv.IsReady())
+	resp, err := http.Get("http://" + addr + "/")
+	assert.NoError(err)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+
+	assert.NoError(<-done)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	assert := a.New(t)
+
+	srv := NewServer(&ServerConfig{})
+
+	rec := httptest.NewRecorder()
+	srv.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+
+	srv.health.MarkStarted()
+	rec = httptest.NewRecorder()
+	srv.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+
+	srv.health.MarkShuttingDown()
+	rec = httptest.NewRecorder()
+	srv.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+}
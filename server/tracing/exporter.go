@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Exporter receives ended spans, e.g. to send them to a collector.
+type Exporter interface {
+	Export(span SpanData) error
+}
+
+// JSONExporter writes each span as a line of JSON. It works without a collector and
+// the output can be read by log shippers.
+type JSONExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewJSONExporter returns an exporter that writes to w.
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+// NewStdoutExporter returns an exporter that writes to stdout.
+func NewStdoutExporter() *JSONExporter {
+	return NewJSONExporter(os.Stdout)
+}
+
+// NewFileExporter returns an exporter that appends to the file at path.
+func NewFileExporter(path string) (*JSONExporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open trace file: %w", err)
+	}
+	return &JSONExporter{w: f, closer: f}, nil
+}
+
+func (e *JSONExporter) Export(span SpanData) error {
+	b, err := json.Marshal(span)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(b)
+	return err
+}
+
+// Close closes the file of an exporter created by NewFileExporter.
+func (e *JSONExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.closer.Close()
+}
@@ -0,0 +1,284 @@
+// Package tracing propagates W3C Trace Context and records spans that are handed to
+// an Exporter when they end.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/stfsy/go-api-kit/utils"
+)
+
+var logger = utils.NewLogger("tracing")
+
+type SpanKind string
+
+const (
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+	SpanKindInternal SpanKind = "internal"
+)
+
+type StatusCode string
+
+const (
+	StatusUnset StatusCode = "unset"
+	StatusOk    StatusCode = "ok"
+	StatusError StatusCode = "error"
+)
+
+// Event is a timestamped annotation of a span.
+type Event struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// SpanData is the immutable record of an ended span that is passed to exporters.
+type SpanData struct {
+	TraceID       TraceID        `json:"trace_id"`
+	SpanID        SpanID         `json:"span_id"`
+	ParentSpanID  SpanID         `json:"parent_span_id"`
+	TraceState    string         `json:"trace_state,omitempty"`
+	Name          string         `json:"name"`
+	Kind          SpanKind       `json:"kind"`
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Events        []Event        `json:"events,omitempty"`
+	Status        StatusCode     `json:"status"`
+	StatusMessage string         `json:"status_message,omitempty"`
+}
+
+func (id TraceID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id SpanID) MarshalText() ([]byte, error) {
+	if !id.IsValid() {
+		return []byte{}, nil
+	}
+	return []byte(id.String()), nil
+}
+
+// Span records a unit of work. All methods are safe to call on a nil span, so code
+// can create child spans without checking whether tracing is enabled. Changes after
+// End are ignored.
+type Span struct {
+	tracer  *Tracer
+	sampled bool
+	flags   byte
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Tracer starts spans and exports them when they end.
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer returns a tracer that hands ended spans to exporter.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start starts a span that is a child of the span or remote span context in ctx and
+// returns a copy of ctx that carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			SpanID: newSpanID(),
+			Name:   name,
+			Kind:   kind,
+			Start:  time.Now(),
+			Status: StatusUnset,
+		},
+	}
+
+	if parent, ok := parentSpanContext(ctx); ok {
+		span.data.TraceID = parent.TraceID
+		span.data.ParentSpanID = parent.SpanID
+		span.data.TraceState = parent.TraceState
+		span.flags = parent.Flags
+		span.sampled = parent.IsSampled()
+	} else {
+		span.data.TraceID = newTraceID()
+		span.flags = flagSampled
+		span.sampled = true
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Start starts a child span of the span in ctx. It returns ctx and a nil span if ctx
+// carries no span, e.g. because tracing is disabled.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, SpanKindInternal)
+}
+
+// RecordError records err on the span in ctx.
+func RecordError(ctx context.Context, err error) {
+	SpanFromContext(ctx).RecordError(err)
+}
+
+type spanContextKey struct{}
+type remoteSpanContextKey struct{}
+
+// SpanFromContext returns the span stored in ctx or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx with a span context received from
+// another service, which becomes the parent of the next span started from ctx.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+func parentSpanContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext(), true
+	}
+	sc, ok := ctx.Value(remoteSpanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// SpanContext returns the propagated part of the span, e.g. to send a traceparent
+// header to another service.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SpanContext{
+		TraceID:    s.data.TraceID,
+		SpanID:     s.data.SpanID,
+		Flags:      s.flags,
+		TraceState: s.data.TraceState,
+	}
+}
+
+// SetName renames the span.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Name = name
+}
+
+// SetAttribute sets an attribute of the span.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = map[string]any{}
+	}
+	s.data.Attributes[key] = value
+}
+
+// AddEvent adds an event to the span.
+func (s *Span) AddEvent(name string, attributes map[string]any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Events = append(s.data.Events, Event{Name: name, Time: time.Now(), Attributes: attributes})
+}
+
+// RecordError adds an exception event for err and sets the status to error.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.AddEvent("exception", map[string]any{
+		"exception.type":    fmt.Sprintf("%T", err),
+		"exception.message": err.Error(),
+	})
+	s.SetStatus(StatusError, err.Error())
+}
+
+// SetStatus sets the status of the span. An ok status is final and an error status is
+// only replaced by ok.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || s.data.Status == StatusOk || (s.data.Status == StatusError && code != StatusOk) {
+		return
+	}
+	s.data.Status = code
+	if code == StatusError {
+		s.data.StatusMessage = message
+	}
+}
+
+// End ends the span and exports it if it is sampled. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if !s.sampled || s.tracer.exporter == nil {
+		return
+	}
+	err := s.tracer.exporter.Export(data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to export span %s: %s", data.Name, err.Error()))
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
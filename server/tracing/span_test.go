@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+type recordingExporter struct {
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(span SpanData) error {
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+func TestTracer_StartsChildOfRemoteParent(t *testing.T) {
+	assert := a.New(t)
+
+	exporter := &recordingExporter{}
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "congo=t61rcWkgMzE"
+	ctx := ContextWithRemoteSpanContext(context.Background(), parent)
+
+	ctx, server := NewTracer(exporter).Start(ctx, "GET /users", SpanKindServer)
+	_, child := Start(ctx, "query")
+	child.End()
+	server.End()
+
+	assert.Len(exporter.spans, 2)
+	assert.Equal(parent.TraceID, exporter.spans[1].TraceID)
+	assert.Equal(parent.SpanID, exporter.spans[1].ParentSpanID)
+	assert.Equal("congo=t61rcWkgMzE", exporter.spans[1].TraceState)
+	assert.Equal(parent.TraceID, exporter.spans[0].TraceID)
+	assert.Equal(exporter.spans[1].SpanID, exporter.spans[0].ParentSpanID)
+	assert.Equal(SpanKindInternal, exporter.spans[0].Kind)
+}
+
+func TestTracer_DoesNotExportUnsampledSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx := ContextWithRemoteSpanContext(context.Background(), parent)
+
+	_, span := NewTracer(exporter).Start(ctx, "GET /users", SpanKindServer)
+	span.End()
+
+	a.Empty(t, exporter.spans)
+}
+
+func TestStart_ReturnsNilSpanWithoutParent(t *testing.T) {
+	assert := a.New(t)
+
+	ctx, span := Start(context.Background(), "query")
+
+	assert.Nil(span)
+	// all methods are safe to call on a nil span
+	span.SetAttribute("key", "value")
+	RecordError(ctx, errors.New("failed"))
+	span.End()
+}
+
+func TestSpan_RecordError(t *testing.T) {
+	assert := a.New(t)
+
+	exporter := &recordingExporter{}
+	ctx, span := NewTracer(exporter).Start(context.Background(), "GET /users", SpanKindServer)
+	RecordError(ctx, errors.New("connection refused"))
+	span.SetStatus(StatusUnset, "")
+	span.End()
+	span.SetAttribute("ignored", true)
+
+	assert.Len(exporter.spans, 1)
+	assert.Equal(StatusError, exporter.spans[0].Status)
+	assert.Equal("connection refused", exporter.spans[0].StatusMessage)
+	assert.Equal("exception", exporter.spans[0].Events[0].Name)
+	assert.Empty(exporter.spans[0].Attributes)
+}
+
+func TestJSONExporter_WritesOneLinePerSpan(t *testing.T) {
+	assert := a.New(t)
+
+	var buf bytes.Buffer
+	tracer := NewTracer(NewJSONExporter(&buf))
+	_, span := tracer.Start(context.Background(), "GET /users", SpanKindServer)
+	span.SetAttribute("http.route", "/users")
+	span.End()
+
+	var data map[string]any
+	assert.NoError(json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal("GET /users", data["name"])
+	assert.Equal(span.SpanContext().TraceID.String(), data["trace_id"])
+	assert.Equal("", data["parent_span_id"])
+	assert.Equal("/users", data["attributes"].(map[string]any)["http.route"])
+	assert.Equal(byte('\n'), buf.Bytes()[buf.Len()-1])
+}
@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	flagSampled = 0x01
+
+	maxTracestateMembers = 32
+	maxTracestateLength  = 512
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+// IsSampled returns true if the sampled flag is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&flagSampled != 0
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value. Values of future versions are
+// accepted if they start with the fields of version 00.
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return sc, fmt.Errorf("traceparent has invalid length")
+	}
+
+	version, err := decodeHex(value[0:2])
+	if err != nil || version[0] == 0xff {
+		return sc, fmt.Errorf("traceparent has invalid version")
+	}
+	if version[0] == 0 && len(value) != 55 {
+		return sc, fmt.Errorf("traceparent has invalid length")
+	}
+	if version[0] > 0 && len(value) > 55 && value[55] != '-' {
+		return sc, fmt.Errorf("traceparent has invalid format")
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, fmt.Errorf("traceparent has invalid format")
+	}
+
+	traceID, err := decodeHex(value[3:35])
+	if err != nil {
+		return sc, fmt.Errorf("traceparent has invalid trace id")
+	}
+	copy(sc.TraceID[:], traceID)
+	if !sc.TraceID.IsValid() {
+		return sc, fmt.Errorf("traceparent has invalid trace id")
+	}
+
+	spanID, err := decodeHex(value[36:52])
+	if err != nil {
+		return sc, fmt.Errorf("traceparent has invalid parent id")
+	}
+	copy(sc.SpanID[:], spanID)
+	if !sc.SpanID.IsValid() {
+		return sc, fmt.Errorf("traceparent has invalid parent id")
+	}
+
+	flags, err := decodeHex(value[53:55])
+	if err != nil {
+		return sc, fmt.Errorf("traceparent has invalid flags")
+	}
+	sc.Flags = flags[0]
+
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex, uppercase hex is invalid in traceparent.
+func decodeHex(s string) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, fmt.Errorf("invalid hex character %q", c)
+		}
+	}
+	return hex.DecodeString(s)
+}
+
+// ValidateTracestate returns value if it is a valid W3C tracestate header value and an
+// empty string otherwise, in which case the tracestate must not be propagated.
+func ValidateTracestate(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > maxTracestateLength {
+		return ""
+	}
+
+	members := strings.Split(value, ",")
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(member, "=")
+		if !ok || !validTracestateKey(key) || !validTracestateValue(val) || seen[key] {
+			return ""
+		}
+		seen[key] = true
+	}
+
+	return value
+}
+
+func validTracestateKey(key string) bool {
+	if key == "" || len(key) > 256 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-' || c == '*' || c == '/' || c == '@' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func validTracestateValue(val string) bool {
+	if val == "" || len(val) > 256 || val[len(val)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
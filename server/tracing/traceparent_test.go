@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"testing"
+
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	assert := a.New(t)
+
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	assert.NoError(err)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal("00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(sc.IsSampled())
+	assert.Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+}
+
+func TestParseTraceparent_AcceptsFutureVersion(t *testing.T) {
+	sc, err := ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+
+	a.NoError(t, err)
+	a.False(t, sc.IsSampled())
+}
+
+func TestParseTraceparent_RejectsInvalidValues(t *testing.T) {
+	for name, value := range map[string]string{
+		"empty":          "",
+		"version ff":     "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"uppercase":      "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"zero trace id":  "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"zero parent id": "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"trailing data":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-x",
+		"bad separator":  "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTraceparent(value)
+			a.Error(t, err)
+		})
+	}
+}
+
+func TestValidateTracestate(t *testing.T) {
+	assert := a.New(t)
+
+	assert.Equal("congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", ValidateTracestate("congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"))
+	assert.Equal("vendor@tenant=x", ValidateTracestate("vendor@tenant=x"))
+	assert.Empty(ValidateTracestate("Congo=t61rcWkgMzE"))
+	assert.Empty(ValidateTracestate("congo=a,congo=b"))
+	assert.Empty(ValidateTracestate("congo"))
+}